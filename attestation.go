@@ -2,14 +2,20 @@ package attestation
 
 import (
 	"encoding/asn1"
+	"fmt"
 )
 
 // OIDKeyAttestationExtension is the key attestation extension.
 var OIDKeyAttestationExtension = asn1.ObjectIdentifier{1, 3, 6, 1, 4, 1, 11129, 2, 1, 17}
 
-// keyDescription reflects the ASN.1 data structure for KeyDescription.
+// KeyDescription reflects the attestation extension content.
+//
+// This sequence of values presents general information about the key pair being verified through
+// key attestation and provides easy access to additional details.
+//
+// Its ASN.1 schema is:
 //
-//	keyDescription ::= SEQUENCE {
+//	KeyDescription ::= SEQUENCE {
 //		attestationVersion         INTEGER, # KM2 value is 1. KM3 value is 2. KM4 value is 3.
 //		attestationSecurityLevel   SecurityLevel,
 //		keymasterVersion           INTEGER,
@@ -19,22 +25,6 @@ var OIDKeyAttestationExtension = asn1.ObjectIdentifier{1, 3, 6, 1, 4, 1, 11129,
 //		softwareEnforced           AuthorizationList,
 //		teeEnforced                AuthorizationList,
 //	}
-type keyDescription struct {
-	Raw                      asn1.RawContent
-	AttestationVersion       int
-	AttestationSecurityLevel asn1.Enumerated
-	KeymasterVersion         int
-	KeymasterSecurityLevel   asn1.Enumerated
-	AttestationChallenge     []byte
-	UniqueId                 []byte
-	SoftwareEnforced         asn1.RawValue
-	TeeEnforced              asn1.RawValue
-}
-
-// KeyDescription reflects the attestation extension content.
-//
-// This sequence of values presents general information about the key pair being verified through
-// key attestation and provides easy access to additional details.
 type KeyDescription struct {
 	Raw                      []byte
 	AttestationVersion       AttestationVersion
@@ -67,8 +57,10 @@ func (v AttestationVersion) String() string {
 		return "KeyMint version 2.0"
 	case KAKeyMintVersion3:
 		return "KeyMint version 3.0"
+	case KAKeyMintVersion4:
+		return "KeyMint version 4.0"
 	default:
-		return ""
+		return fmt.Sprintf("AttestationVersion(%d)", uint(v))
 	}
 }
 
@@ -77,9 +69,10 @@ const (
 	KAKeymasterVersion3                                // Keymaster version 3.0
 	KAKeymasterVersion4                                // Keymaster version 4.0
 	KAKeymasterVersion41                               // Keymaster version 4.1
-	KAKeyMintVersion1    = 100                         // KeyMint version 1.0
-	KAKeyMintVersion2    = 200                         // KeyMint version 2.0
-	KAKeyMintVersion3    = 300                         // KeyMint version 3.0
+	KAKeyMintVersion1    AttestationVersion = 100      // KeyMint version 1.0
+	KAKeyMintVersion2    AttestationVersion = 200      // KeyMint version 2.0
+	KAKeyMintVersion3    AttestationVersion = 300      // KeyMint version 3.0
+	KAKeyMintVersion4    AttestationVersion = 400      // KeyMint version 4.0
 )
 
 // KeymasterVersion is the version of the Keymaster or KeyMint hardware abstraction layer.
@@ -106,8 +99,10 @@ func (v KeymasterVersion) String() string {
 		return "KeyMint version 2.0"
 	case KeyMintVersion3:
 		return "KeyMint version 3.0"
+	case KeyMintVersion4:
+		return "KeyMint version 4.0"
 	default:
-		return ""
+		return fmt.Sprintf("KeymasterVersion(%d)", uint(v))
 	}
 }
 
@@ -117,10 +112,11 @@ const (
 	KeymasterVersion2                          // Keymaster version 2.0
 	KeymasterVersion3                          // Keymaster version 3.0
 	KeymasterVersion4                          // Keymaster version 4.0
-	KeymasterVersion41 = 41                    // Keymaster version 4.1
-	KeyMintVersion1    = 100                   // KeyMint version 1.0
-	KeyMintVersion2    = 200                   // KeyMint version 2.0
-	KeyMintVersion3    = 300                   // KeyMint version 3.0
+	KeymasterVersion41 KeymasterVersion = 41   // Keymaster version 4.1
+	KeyMintVersion1    KeymasterVersion = 100  // KeyMint version 1.0
+	KeyMintVersion2    KeymasterVersion = 200  // KeyMint version 2.0
+	KeyMintVersion3    KeymasterVersion = 300  // KeyMint version 3.0
+	KeyMintVersion4    KeymasterVersion = 400  // KeyMint version 4.0
 )
 
 // SecurityLevel reflects the ASN.1 data structure for SecurityLevel.
@@ -135,13 +131,31 @@ const (
 //	}
 type SecurityLevel uint
 
+var securityLevelNames = map[SecurityLevel]string{
+	Software:           "Software",
+	TrustedEnvironment: "TrustedEnvironment",
+	StrongBox:          "StrongBox",
+	Keystore:           "Keystore",
+}
+
 // String returns the string representation.
 func (l SecurityLevel) String() string {
-	return [...]string{
-		"Software",
-		"TrustedEnvironment",
-		"StrongBox",
-	}[l]
+	return enumString("SecurityLevel", securityLevelNames, l)
+}
+
+// MarshalText implements the encoding.TextMarshaler interface.
+func (l SecurityLevel) MarshalText() ([]byte, error) {
+	return []byte(l.String()), nil
+}
+
+// UnmarshalText implements the encoding.TextUnmarshaler interface.
+func (l *SecurityLevel) UnmarshalText(text []byte) error {
+	v, err := ParseSecurityLevel(string(text))
+	if err != nil {
+		return err
+	}
+	*l = v
+	return nil
 }
 
 // The security level of the attestation.
@@ -149,22 +163,37 @@ const (
 	Software SecurityLevel = iota
 	TrustedEnvironment
 	StrongBox
+	Keystore SecurityLevel = 100 // KeyMint only, never used in attestation records.
 )
 
-// authorizationList reflects the ASN.1 data structure for AuthorizationList.
+// ParseSecurityLevel parses the string representation of a SecurityLevel.
+func ParseSecurityLevel(s string) (SecurityLevel, error) {
+	return parseEnum("SecurityLevel", securityLevelNames, s)
+}
+
+// AuthorizationList reflects the key pair's properties as defined in the Keymaster or KeyMint
+// hardware abstraction layer.
+//
+// Its ASN.1 schema is:
 //
 //	AuthorizationList ::= SEQUENCE {
 //		purpose                     [1] EXPLICIT SET OF INTEGER OPTIONAL,
 //		algorithm                   [2] EXPLICIT INTEGER OPTIONAL,
 //		keySize                     [3] EXPLICIT INTEGER OPTIONAL.
+//		blockMode                   [4] EXPLICIT SET OF INTEGER OPTIONAL,
 //		digest                      [5] EXPLICIT SET OF INTEGER OPTIONAL,
 //		padding                     [6] EXPLICIT SET OF INTEGER OPTIONAL,
+//		callerNonce                 [7] EXPLICIT NULL OPTIONAL,
+//		minMacLength                [8] EXPLICIT INTEGER OPTIONAL,
 //		ecCurve                     [10] EXPLICIT INTEGER OPTIONAL,
 //		rsaPublicExponent           [200] EXPLICIT INTEGER OPTIONAL,
+//		mgfDigest                   [203] EXPLICIT SET OF INTEGER OPTIONAL, # KM100
 //		rollbackResistance          [303] EXPLICIT NULL OPTIONAL, # KM4
+//		earlyBootOnly               [305] EXPLICIT NULL OPTIONAL, # KM4
 //		activeDateTime              [400] EXPLICIT INTEGER OPTIONAL
 //		originationExpireDateTime   [401] EXPLICIT INTEGER OPTIONAL
 //		usageExpireDateTime         [402] EXPLICIT INTEGER OPTIONAL
+//		usageCountLimit             [405] EXPLICIT INTEGER OPTIONAL, # KM100
 //		noAuthRequired              [503] EXPLICIT NULL OPTIONAL,
 //		userAuthType                [504] EXPLICIT INTEGER OPTIONAL,
 //		authTimeout                 [505] EXPLICIT INTEGER OPTIONAL,
@@ -191,63 +220,30 @@ const (
 //		attestationIdModel          [717] EXPLICIT OCTET_STRING OPTIONAL, # KM3
 //		vendorPatchLevel            [718] EXPLICIT INTEGER OPTIONAL, # KM4
 //		bootPatchLevel              [719] EXPLICIT INTEGER OPTIONAL, # KM4
+//		deviceUniqueAttestation     [720] EXPLICIT NULL OPTIONAL, # KM4
+//		identityCredentialKey       [721] EXPLICIT NULL OPTIONAL, # KM100
+//		attestationIdSecondImei     [723] EXPLICIT OCTET_STRING OPTIONAL, # KM300
+//		moduleHash                  [724] EXPLICIT OCTET_STRING OPTIONAL, # KM400
 //	}
-type authorizationList struct {
-	Raw                         asn1.RawContent
-	Purpose                     []int32         `asn1:"explicit,optional,omitempty,set,tag:1"` // [1] EXPLICIT SET OF INTEGER OPTIONAL,
-	Algorithm                   asn1.RawValue   `asn1:"explicit,optional,tag:2"`               // [2] EXPLICIT INTEGER OPTIONAL,
-	KeySize                     asn1.RawValue   `asn1:"explicit,optional,tag:3"`               // [3] EXPLICIT INTEGER OPTIONAL.
-	Digest                      []int           `asn1:"explicit,optional,omitempty,set,tag:5"` // [5] EXPLICIT SET OF INTEGER OPTIONAL,
-	Padding                     []int           `asn1:"explicit,optional,omitempty,set,tag:6"` // [6] EXPLICIT SET OF INTEGER OPTIONAL,
-	EcCurve                     asn1.RawValue   `asn1:"explicit,optional,tag:10"`              // [10] EXPLICIT INTEGER OPTIONAL,
-	RsaPublicExponent           asn1.RawValue   `asn1:"explicit,optional,tag:200"`             // [200] EXPLICIT INTEGER OPTIONAL,
-	RollbackResistance          asn1.RawValue   `asn1:"explicit,optional,tag:303"`             // [303] EXPLICIT NULL OPTIONAL, # KM4
-	ActiveDateTime              asn1.RawValue   `asn1:"explicit,optional,tag:400"`             // [400] EXPLICIT INTEGER OPTIONAL
-	OriginationExpireDateTime   asn1.RawValue   `asn1:"explicit,optional,tag:401"`             // [401] EXPLICIT INTEGER OPTIONAL
-	UsageExpireDateTime         asn1.RawValue   `asn1:"explicit,optional,tag:402"`             // [402] EXPLICIT INTEGER OPTIONAL
-	NoAuthRequired              asn1.RawValue   `asn1:"explicit,optional,tag:503"`             // [503] EXPLICIT NULL OPTIONAL,
-	UserAuthType                asn1.RawValue   `asn1:"explicit,optional,tag:504"`             // [504] EXPLICIT INTEGER OPTIONAL,
-	AuthTimeout                 asn1.RawValue   `asn1:"explicit,optional,tag:505"`             // [505] EXPLICIT INTEGER OPTIONAL,
-	AllowWhileOnBody            asn1.RawValue   `asn1:"explicit,optional,tag:506"`             // [506] EXPLICIT NULL OPTIONAL,
-	TrustedUserPresenceRequired asn1.RawValue   `asn1:"explicit,optional,tag:507"`             // [507] EXPLICIT NULL OPTIONAL, # KM4
-	TrustedConfirmationRequired asn1.RawValue   `asn1:"explicit,optional,tag:508"`             // [508] EXPLICIT NULL OPTIONAL, # KM4
-	UnlockedDeviceRequired      asn1.RawValue   `asn1:"explicit,optional,tag:509"`             // [509] EXPLICIT NULL OPTIONAL, # KM4
-	AllApplications             asn1.RawValue   `asn1:"explicit,optional,tag:600"`             // [600] EXPLICIT NULL OPTIONAL,
-	ApplicationId               []byte          `asn1:"explicit,optional,omitempty,tag:601"`   // [601] EXPLICIT OCTET_STRING OPTIONAL,
-	CreationDateTime            asn1.RawValue   `asn1:"explicit,optional,tag:701"`             // [701] EXPLICIT INTEGER OPTIONAL,
-	Origin                      asn1.RawValue   `asn1:"explicit,optional,tag:702"`             // [702] EXPLICIT INTEGER OPTIONAL,
-	RollbackResistant           asn1.RawValue   `asn1:"explicit,optional,tag:703"`             // [703] EXPLICIT NULL OPTIONAL, # KM2 and KM3 only.
-	RootOfTrust                 asn1.RawValue   `asn1:"explicit,optional,tag:704"`             // [704] EXPLICIT RootOfTrust OPTIONAL,
-	OsVersion                   asn1.RawValue   `asn1:"explicit,optional,tag:705"`             // [705] EXPLICIT INTEGER OPTIONAL,
-	OsPatchLevel                asn1.RawValue   `asn1:"explicit,optional,tag:706"`             // [706] EXPLICIT INTEGER OPTIONAL,
-	AttestationApplicationId    asn1.RawContent `asn1:"explicit,optional,tag:709"`             // [709] EXPLICIT OCTET_STRING OPTIONAL, # KM3
-	AttestationIdBrand          []byte          `asn1:"explicit,optional,omitempty,tag:710"`   // [710] EXPLICIT OCTET_STRING OPTIONAL, # KM3
-	AttestationIdDevice         []byte          `asn1:"explicit,optional,omitempty,tag:711"`   // [711] EXPLICIT OCTET_STRING OPTIONAL, # KM3
-	AttestationIdProduct        []byte          `asn1:"explicit,optional,omitempty,tag:712"`   // [712] EXPLICIT OCTET_STRING OPTIONAL, # KM3
-	AttestationIdSerial         []byte          `asn1:"explicit,optional,omitempty,tag:713"`   // [713] EXPLICIT OCTET_STRING OPTIONAL, # KM3
-	AttestationIdImei           []byte          `asn1:"explicit,optional,omitempty,tag:714"`   // [714] EXPLICIT OCTET_STRING OPTIONAL, # KM3
-	AttestationIdMeid           []byte          `asn1:"explicit,optional,omitempty,tag:715"`   // [715] EXPLICIT OCTET_STRING OPTIONAL, # KM3
-	AttestationIdManufacturer   []byte          `asn1:"explicit,optional,omitempty,tag:716"`   // [716] EXPLICIT OCTET_STRING OPTIONAL, # KM3
-	AttestationIdModel          []byte          `asn1:"explicit,optional,omitempty,tag:717"`   // [717] EXPLICIT OCTET_STRING OPTIONAL, # KM3
-	VendorPatchLevel            asn1.RawValue   `asn1:"explicit,optional,tag:718"`             // [718] EXPLICIT INTEGER OPTIONAL, # KM4
-	BootPatchLevel              asn1.RawValue   `asn1:"explicit,optional,tag:719"`             // [719] EXPLICIT INTEGER OPTIONAL, # KM4
-}
-
-// AuthorizationList reflects the key pair's properties as defined in the Keymaster or KeyMint
-// hardware abstraction layer.
 type AuthorizationList struct {
 	Raw                         []byte
 	Purpose                     []KeyPurpose
 	Algorithm                   *Algorithm
 	KeySize                     *int
+	BlockMode                   []BlockMode
 	Digest                      []Digest
 	Padding                     []PaddingMode
+	CallerNonce                 bool
+	MinMacLength                *int
 	EcCurve                     *EcCurve
 	RsaPublicExponent           *int64
+	MgfDigest                   []Digest
 	RollbackResistance          bool
-	ActiveDateTime              *int64
-	OriginationExpireDateTime   *int
-	UsageExpireDateTime         *int64
+	EarlyBootOnly               bool
+	ActiveDateTime              *DateTime
+	OriginationExpireDateTime   *DateTime
+	UsageExpireDateTime         *DateTime
+	UsageCountLimit             *int
 	NoAuthRequired              bool
 	UserAuthType                *HardwareAuthenticatorType
 	AuthTimeout                 *int32
@@ -257,12 +253,12 @@ type AuthorizationList struct {
 	UnlockedDeviceRequired      bool
 	AllApplications             bool
 	ApplicationId               []byte
-	CreationDateTime            *int
+	CreationDateTime            *DateTime
 	Origin                      *KeyOrigin
 	RollbackResistant           bool
 	RootOfTrust                 *RootOfTrust
-	OsVersion                   *int
-	OsPatchLevel                *int
+	OsVersion                   *OsVersion
+	OsPatchLevel                *PatchLevel
 	AttestationApplicationId    *AttestationApplicationId
 	AttestationIdBrand          []byte
 	AttestationIdDevice         []byte
@@ -272,11 +268,28 @@ type AuthorizationList struct {
 	AttestationIdMeid           []byte
 	AttestationIdManufacturer   []byte
 	AttestationIdModel          []byte
-	VendorPatchLevel            *int
-	BootPatchLevel              *int
+	VendorPatchLevel            *PatchLevel
+	BootPatchLevel              *PatchLevel
+	DeviceUniqueAttestation     bool
+	IdentityCredentialKey       bool
+	AttestationIdSecondImei     []byte
+	ModuleHash                  []byte
+	Unknown                     []RawTag
+}
+
+// RawTag reflects an authorization tag unknown to this package, such as a tag introduced by a
+// newer Android release. Value is the DER encoding of the tagged value, without the explicit tag.
+//
+// The explicit tag is always encoded as constructed. A primitive explicit tag, which is a warning
+// in lenient mode, is therefore re-encoded as constructed by CreateKeyDescription.
+type RawTag struct {
+	Tag   int
+	Value []byte
 }
 
-// RootOfTrust reflects the ASN.1 data structure for RootOfTrust.
+// RootOfTrust reflects information on Android secure boot.
+//
+// Its ASN.1 schema is:
 //
 //	RootOfTrust ::= SEQUENCE {
 //		verifiedBootKey            OCTET_STRING,
@@ -284,15 +297,6 @@ type AuthorizationList struct {
 //		verifiedBootState          VerifiedBootState,
 //		verifiedBootHash           OCTET_STRING, # KM4
 //	}
-type rootOfTrust struct {
-	Raw               asn1.RawContent
-	VerifiedBootKey   []byte
-	DeviceLocked      bool
-	VerifiedBootState asn1.Enumerated
-	VerifiedBootHash  []byte `asn1:"optional,omitempty"`
-}
-
-// RootOfTrust reflects information on Android secure boot.
 type RootOfTrust struct {
 	Raw               []byte
 	VerifiedBootKey   []byte
@@ -311,56 +315,69 @@ type RootOfTrust struct {
 //	}
 type VerifiedBootState uint
 
+var verifiedBootStateNames = map[VerifiedBootState]string{
+	Verified:   "Verified",
+	SelfSigned: "SelfSigned",
+	Unverified: "Unverified",
+	Failed:     "Failed",
+}
+
 // String returns the string representation.
 func (s VerifiedBootState) String() string {
-	return [...]string{
-		"Verified",
-		"SelfSigned",
-		"Unverified",
-		"Failed",
-	}[s]
+	return enumString("VerifiedBootState", verifiedBootStateNames, s)
+}
+
+// MarshalText implements the encoding.TextMarshaler interface.
+func (s VerifiedBootState) MarshalText() ([]byte, error) {
+	return []byte(s.String()), nil
+}
+
+// UnmarshalText implements the encoding.TextUnmarshaler interface.
+func (s *VerifiedBootState) UnmarshalText(text []byte) error {
+	v, err := ParseVerifiedBootState(string(text))
+	if err != nil {
+		return err
+	}
+	*s = v
+	return nil
 }
 
 // VerifiedBootState is the state of verified boot.
 const (
-	Verified   = iota // Indicates a full chain of trust, which includes the bootloader, the boot partition, and all verified partitions.
-	SelfSigned        // Indicates that the device-embedded certificate has verified the device's boot partition and that the signature is valid.
-	Unverified        // Indicates that the user can modify the device freely. Therefore, the user is responsible for verifying the device's integrity.
-	Failed            // Indicates that the device has failed verification. The attestation certificate should never use this value for VerifiedBootState.
+	Verified   VerifiedBootState = iota // Indicates a full chain of trust, which includes the bootloader, the boot partition, and all verified partitions.
+	SelfSigned                          // Indicates that the device-embedded certificate has verified the device's boot partition and that the signature is valid.
+	Unverified                          // Indicates that the user can modify the device freely. Therefore, the user is responsible for verifying the device's integrity.
+	Failed                              // Indicates that the device has failed verification. The attestation certificate should never use this value for VerifiedBootState.
 )
 
-// attestationApplicationId reflects the ASN.1 data structure for AttestationApplicationId.
+// ParseVerifiedBootState parses the string representation of a VerifiedBootState.
+func ParseVerifiedBootState(s string) (VerifiedBootState, error) {
+	return parseEnum("VerifiedBootState", verifiedBootStateNames, s)
+}
+
+// AttestationApplicationId reflects the Android platform's belief as to which apps are allowed to
+// use the secret key material under attestation. The ID can comprise multiple packages if and only
+// if multiple packages share the same UID.
+//
+// Its ASN.1 schema is:
 //
 //	AttestationApplicationId ::= SEQUENCE {
 //	    package_infos  SET OF AttestationPackageInfo,
 //	    signature_digests  SET OF OCTET_STRING,
 //	}
-type attestationApplicationId struct {
-	Raw              asn1.RawContent
-	PackageInfos     []attestationPackageInfo `asn1:"set"`
-	SignatureDigests [][]byte                 `asn1:"set"`
+type AttestationApplicationId struct {
+	PackageInfos     []*AttestationPackageInfo
+	SignatureDigests [][]byte
 }
 
-// attestationPackageInfo reflects the ASN.1 data structure for AttestationPackageInfo.
+// AttestationPackageInfo reflects a package's name and version number.
+//
+// Its ASN.1 schema is:
 //
 //	AttestationPackageInfo ::= SEQUENCE {
 //	    package_name  OCTET_STRING,
 //	    version  INTEGER,
 //	}
-type attestationPackageInfo struct {
-	PackageName []byte
-	Version     int
-}
-
-// AttestationApplicationId reflects the Android platform's belief as to which apps are allowed to
-// use the secret key material under attestation. The ID can comprise multiple packages if and only
-// if multiple packages share the same UID.
-type AttestationApplicationId struct {
-	PackageInfos     []*AttestationPackageInfo
-	SignatureDigests [][]byte
-}
-
-// AttestationPackageInfo reflects a package's name and version number.
 type AttestationPackageInfo struct {
 	PackageName string
 	Version     int
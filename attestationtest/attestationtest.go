@@ -0,0 +1,308 @@
+// Package attestationtest provides utilities for testing Android key attestation verification.
+//
+// It creates certificate chains similar to the ones returned by Android's KeyStore: a leaf
+// certificate carrying the key attestation extension, one or more intermediate certificates and a
+// self-signed test root. The test root is not trusted by attestation.Verify unless it is passed in
+// attestation.VerifyOptions.Roots.
+package attestationtest
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"math/big"
+	"time"
+
+	"github.com/mbreban/attestation"
+)
+
+// oidTitle is the X.520 title attribute used by intermediate certificates to hold the security
+// level, e.g. TEE or StrongBox.
+var oidTitle = asn1.ObjectIdentifier{2, 5, 4, 12}
+
+// Options contains parameters for NewChain.
+type Options struct {
+	// Algorithm is the algorithm of the leaf key, either attestation.AlgoEC or
+	// attestation.AlgoRSA. If zero, attestation.AlgoEC is used.
+	Algorithm attestation.Algorithm
+	// KeySize is the size of the leaf key in bits. If zero, 256 is used for EC keys and 2048 for
+	// RSA keys.
+	KeySize int
+	// StrongBox creates a StrongBox attestation instead of a TEE attestation.
+	StrongBox bool
+	// Intermediates is the number of intermediate certificates. If zero, one intermediate
+	// certificate is created.
+	Intermediates int
+	// Now is the time the chain is valid at. If zero, the current time is used.
+	Now time.Time
+
+	// BadSignature corrupts the signature of the leaf certificate.
+	BadSignature bool
+	// Expired makes the leaf certificate expire before Now.
+	Expired bool
+	// MissingExtension omits the key attestation extension from the leaf certificate.
+	MissingExtension bool
+}
+
+// Chain is an attestation certificate chain and its private keys.
+type Chain struct {
+	// Certificates is the chain in the order returned by Android's KeyStore, the leaf first and
+	// the root last.
+	Certificates []*x509.Certificate
+	// Keys holds the private key of each certificate of the chain.
+	Keys []crypto.Signer
+	// KeyDescription is the content of the key attestation extension of the leaf.
+	KeyDescription *attestation.KeyDescription
+}
+
+// Leaf returns the leaf certificate.
+func (c *Chain) Leaf() *x509.Certificate {
+	return c.Certificates[0]
+}
+
+// Root returns the root certificate.
+func (c *Chain) Root() *x509.Certificate {
+	return c.Certificates[len(c.Certificates)-1]
+}
+
+// NewChain creates an attestation certificate chain whose leaf carries a key attestation
+// extension built from template.
+//
+// The security levels of template are set to TrustedEnvironment, or StrongBox with
+// opts.StrongBox. Algorithm, KeySize and EcCurve are added to the hardware-enforced list to match
+// the leaf key unless already set in either list. template is not modified. If template is nil, a
+// KeyMint 3 attestation of a signing key is created.
+func NewChain(template *attestation.KeyDescription, opts Options) (*Chain, error) {
+	now := opts.Now
+	if now.IsZero() {
+		now = time.Now()
+	}
+
+	intermediates := opts.Intermediates
+	if intermediates == 0 {
+		intermediates = 1
+	}
+
+	securityLevel, title := attestation.TrustedEnvironment, "TEE"
+	if opts.StrongBox {
+		securityLevel, title = attestation.StrongBox, "StrongBox"
+	}
+
+	leafKey, err := generateKey(opts.Algorithm, opts.KeySize)
+	if err != nil {
+		return nil, err
+	}
+
+	keyDesc := newKeyDescription(template, securityLevel, leafKey)
+
+	// The root and intermediates are created first, the root last in the chain.
+	rootKey, err := generateKey(attestation.AlgoEC, 256)
+	if err != nil {
+		return nil, err
+	}
+	root, err := createCertificate(&x509.Certificate{
+		SerialNumber:          randomSerial(),
+		Subject:               pkix.Name{SerialNumber: randomHex(8)},
+		NotBefore:             now.AddDate(-1, 0, 0),
+		NotAfter:              now.AddDate(20, 0, 0),
+		KeyUsage:              x509.KeyUsageCertSign | x509.KeyUsageCRLSign,
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+	}, nil, rootKey, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	certs := []*x509.Certificate{root}
+	keys := []crypto.Signer{rootKey}
+	for i := 0; i < intermediates; i++ {
+		key, err := generateKey(attestation.AlgoEC, 256)
+		if err != nil {
+			return nil, err
+		}
+		crt, err := createCertificate(&x509.Certificate{
+			SerialNumber: randomSerial(),
+			Subject: pkix.Name{
+				SerialNumber: randomHex(16),
+				ExtraNames:   []pkix.AttributeTypeAndValue{{Type: oidTitle, Value: title}},
+			},
+			NotBefore:             now.AddDate(-1, 0, 0),
+			NotAfter:              now.AddDate(10, 0, 0),
+			KeyUsage:              x509.KeyUsageCertSign | x509.KeyUsageCRLSign,
+			BasicConstraintsValid: true,
+			IsCA:                  true,
+		}, certs[0], key, keys[0])
+		if err != nil {
+			return nil, err
+		}
+		certs = append([]*x509.Certificate{crt}, certs...)
+		keys = append([]crypto.Signer{key}, keys...)
+	}
+
+	// KeyMint uses these validity dates when no CERTIFICATE_NOT_BEFORE or CERTIFICATE_NOT_AFTER
+	// tag is provided.
+	leafTemplate := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "Android Keystore Key"},
+		NotBefore:    time.Unix(0, 0).UTC(),
+		NotAfter:     time.Date(2048, 1, 1, 0, 0, 0, 0, time.UTC),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+	}
+	if opts.Expired {
+		leafTemplate.NotBefore = now.AddDate(0, 0, -2)
+		leafTemplate.NotAfter = now.AddDate(0, 0, -1)
+	}
+	if !opts.MissingExtension {
+		ext, err := attestation.CreateExtension(keyDesc)
+		if err != nil {
+			return nil, err
+		}
+		leafTemplate.ExtraExtensions = []pkix.Extension{*ext}
+	}
+
+	leaf, err := createCertificate(leafTemplate, certs[0], leafKey, keys[0])
+	if err != nil {
+		return nil, err
+	}
+
+	if opts.BadSignature {
+		derBytes := append([]byte(nil), leaf.Raw...)
+		derBytes[len(derBytes)-1] ^= 0xff
+		leaf, err = x509.ParseCertificate(derBytes)
+		if err != nil {
+			return nil, fmt.Errorf("attestationtest: %v", err)
+		}
+	}
+
+	return &Chain{
+		Certificates:   append([]*x509.Certificate{leaf}, certs...),
+		Keys:           append([]crypto.Signer{leafKey}, keys...),
+		KeyDescription: keyDesc,
+	}, nil
+}
+
+// newKeyDescription returns a copy of template with the given security level and the key
+// properties of key.
+func newKeyDescription(template *attestation.KeyDescription, securityLevel attestation.SecurityLevel, key crypto.Signer) *attestation.KeyDescription {
+	var keyDesc attestation.KeyDescription
+	if template != nil {
+		keyDesc = *template
+	} else {
+		keyDesc = attestation.KeyDescription{
+			AttestationVersion:   attestation.KAKeyMintVersion3,
+			KeymasterVersion:     attestation.KeyMintVersion3,
+			AttestationChallenge: []byte("challenge"),
+			TeeEnforced: attestation.AuthorizationList{
+				Purpose:        []attestation.KeyPurpose{attestation.PurposeSign, attestation.PurposeVerify},
+				NoAuthRequired: true,
+			},
+		}
+		origin := attestation.KeyOriginGenerated
+		keyDesc.TeeEnforced.Origin = &origin
+	}
+	keyDesc.Raw = nil
+	keyDesc.AttestationSecurityLevel = securityLevel
+	keyDesc.KeymasterSecurityLevel = securityLevel
+
+	sw, hw := &keyDesc.SoftwareEnforced, &keyDesc.TeeEnforced
+
+	var algorithm attestation.Algorithm
+	var keySize int
+	var ecCurve *attestation.EcCurve
+	switch k := key.(type) {
+	case *rsa.PrivateKey:
+		algorithm, keySize = attestation.AlgoRSA, k.N.BitLen()
+	case *ecdsa.PrivateKey:
+		algorithm, keySize = attestation.AlgoEC, k.Curve.Params().BitSize
+		if curve, ok := attestation.EcCurveOf(k.Curve); ok {
+			ecCurve = &curve
+		}
+	}
+
+	if sw.Algorithm == nil && hw.Algorithm == nil {
+		hw.Algorithm = &algorithm
+	}
+	if sw.KeySize == nil && hw.KeySize == nil {
+		hw.KeySize = &keySize
+	}
+	if ecCurve != nil && sw.EcCurve == nil && hw.EcCurve == nil {
+		hw.EcCurve = ecCurve
+	}
+
+	return &keyDesc
+}
+
+// generateKey generates a key of the given algorithm and size.
+func generateKey(algorithm attestation.Algorithm, keySize int) (crypto.Signer, error) {
+	switch algorithm {
+	case 0, attestation.AlgoEC:
+		var curve elliptic.Curve
+		switch keySize {
+		case 0, 256:
+			curve = elliptic.P256()
+		case 224:
+			curve = elliptic.P224()
+		case 384:
+			curve = elliptic.P384()
+		case 521:
+			curve = elliptic.P521()
+		default:
+			return nil, fmt.Errorf("attestationtest: unsupported EC key size %d", keySize)
+		}
+		return ecdsa.GenerateKey(curve, rand.Reader)
+	case attestation.AlgoRSA:
+		if keySize == 0 {
+			keySize = 2048
+		}
+		return rsa.GenerateKey(rand.Reader, keySize)
+	default:
+		return nil, errors.New("attestationtest: unsupported algorithm " + algorithm.String())
+	}
+}
+
+// createCertificate creates a certificate for key, issued by parent or self-signed if parent is
+// nil.
+func createCertificate(template, parent *x509.Certificate, key, parentKey crypto.Signer) (*x509.Certificate, error) {
+	if parent == nil {
+		parent, parentKey = template, key
+	}
+
+	derBytes, err := x509.CreateCertificate(rand.Reader, template, parent, key.Public(), parentKey)
+	if err != nil {
+		return nil, fmt.Errorf("attestationtest: %v", err)
+	}
+
+	crt, err := x509.ParseCertificate(derBytes)
+	if err != nil {
+		return nil, fmt.Errorf("attestationtest: %v", err)
+	}
+
+	return crt, nil
+}
+
+// randomSerial returns a random positive 128-bit serial number.
+func randomSerial() *big.Int {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		panic(err)
+	}
+	b[0] &= 0x7f
+	return new(big.Int).SetBytes(b)
+}
+
+// randomHex returns n random bytes encoded in hexadecimal.
+func randomHex(n int) string {
+	b := make([]byte, n)
+	if _, err := rand.Read(b); err != nil {
+		panic(err)
+	}
+	return hex.EncodeToString(b)
+}
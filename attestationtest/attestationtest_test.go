@@ -0,0 +1,178 @@
+package attestationtest
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"errors"
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/mbreban/attestation"
+)
+
+func TestNewChain(t *testing.T) {
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name              string
+		opts              Options
+		wantLen           int
+		wantSecurityLevel attestation.SecurityLevel
+		wantErr           error
+	}{
+		{
+			name:              "shouldVerifyDefault",
+			opts:              Options{},
+			wantLen:           3,
+			wantSecurityLevel: attestation.TrustedEnvironment,
+		},
+		{
+			name:              "shouldVerifyStrongBoxRSA",
+			opts:              Options{Algorithm: attestation.AlgoRSA, StrongBox: true},
+			wantLen:           3,
+			wantSecurityLevel: attestation.StrongBox,
+		},
+		{
+			name:              "shouldVerifyEC384WithTwoIntermediates",
+			opts:              Options{KeySize: 384, Intermediates: 2},
+			wantLen:           4,
+			wantSecurityLevel: attestation.TrustedEnvironment,
+		},
+		{
+			name:    "shouldFailWithBadSignature",
+			opts:    Options{BadSignature: true},
+			wantLen: 3,
+			wantErr: attestation.ErrInvalidChain,
+		},
+		{
+			name:    "shouldFailWhenExpired",
+			opts:    Options{Expired: true},
+			wantLen: 3,
+			wantErr: attestation.ErrCertificateExpired,
+		},
+		{
+			name:    "shouldFailWithMissingExtension",
+			opts:    Options{MissingExtension: true},
+			wantLen: 3,
+			wantErr: attestation.ErrMissingExtension,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.opts.Now = now
+
+			chain, err := NewChain(nil, tt.opts)
+			if err != nil {
+				t.Fatalf("NewChain() error = %v", err)
+			}
+			if len(chain.Certificates) != tt.wantLen || len(chain.Keys) != tt.wantLen {
+				t.Fatalf("NewChain() got %d certificates and %d keys, want %d", len(chain.Certificates), len(chain.Keys), tt.wantLen)
+			}
+
+			keyDesc, err := attestation.Verify(chain.Certificates, attestation.VerifyOptions{
+				Roots:              []*x509.Certificate{chain.Root()},
+				CurrentTime:        now,
+				CheckKeyProperties: true,
+			})
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("Verify() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err != nil {
+				return
+			}
+
+			if keyDesc.AttestationSecurityLevel != tt.wantSecurityLevel || keyDesc.KeymasterSecurityLevel != tt.wantSecurityLevel {
+				t.Errorf("Verify() security levels = %v, %v, want %v", keyDesc.AttestationSecurityLevel, keyDesc.KeymasterSecurityLevel, tt.wantSecurityLevel)
+			}
+			if chain.Leaf().Subject.CommonName != "Android Keystore Key" {
+				t.Errorf("Leaf() subject = %q, want %q", chain.Leaf().Subject.CommonName, "Android Keystore Key")
+			}
+		})
+	}
+}
+
+func TestNewChain_template(t *testing.T) {
+	ecCurve := attestation.CurveP384
+	template := &attestation.KeyDescription{
+		AttestationVersion:   attestation.KAKeyMintVersion2,
+		KeymasterVersion:     attestation.KeyMintVersion2,
+		AttestationChallenge: []byte("abc"),
+		SoftwareEnforced:     attestation.AuthorizationList{EcCurve: &ecCurve},
+	}
+
+	chain, err := NewChain(template, Options{})
+	if err != nil {
+		t.Fatalf("NewChain() error = %v", err)
+	}
+
+	if template.AttestationSecurityLevel != attestation.Software || template.TeeEnforced.Algorithm != nil {
+		t.Errorf("NewChain() modified the template")
+	}
+
+	keyDesc := chain.KeyDescription
+	if keyDesc.AttestationVersion != attestation.KAKeyMintVersion2 || string(keyDesc.AttestationChallenge) != "abc" {
+		t.Errorf("NewChain() KeyDescription = %+v, want template values", keyDesc)
+	}
+	if keyDesc.TeeEnforced.EcCurve != nil {
+		t.Errorf("NewChain() TeeEnforced.EcCurve = %v, want nil", *keyDesc.TeeEnforced.EcCurve)
+	}
+	if keyDesc.TeeEnforced.Algorithm == nil || *keyDesc.TeeEnforced.Algorithm != attestation.AlgoEC {
+		t.Errorf("NewChain() TeeEnforced.Algorithm = %v, want %v", keyDesc.TeeEnforced.Algorithm, attestation.AlgoEC)
+	}
+
+	// The P-384 curve of the template does not match the default P-256 key.
+	err = attestation.CheckKeyProperties(chain.Leaf(), keyDesc)
+	if !errors.Is(err, attestation.ErrKeyMismatch) {
+		t.Errorf("CheckKeyProperties() error = %v, wantErr %v", err, attestation.ErrKeyMismatch)
+	}
+}
+
+// TestVerify_forgedByAttestedKey checks that the attested leaf key cannot issue a certificate
+// carrying a forged KeyDescription.
+func TestVerify_forgedByAttestedKey(t *testing.T) {
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	chain, err := NewChain(nil, Options{Now: now})
+	if err != nil {
+		t.Fatalf("NewChain() error = %v", err)
+	}
+
+	forged := *chain.KeyDescription
+	forged.AttestationChallenge = []byte("forged")
+	ext, err := attestation.CreateExtension(&forged)
+	if err != nil {
+		t.Fatalf("CreateExtension() error = %v", err)
+	}
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	derBytes, err := x509.CreateCertificate(rand.Reader, &x509.Certificate{
+		SerialNumber:    big.NewInt(1),
+		Subject:         pkix.Name{CommonName: "Android Keystore Key"},
+		NotBefore:       now.AddDate(0, 0, -1),
+		NotAfter:        now.AddDate(1, 0, 0),
+		KeyUsage:        x509.KeyUsageDigitalSignature,
+		ExtraExtensions: []pkix.Extension{*ext},
+	}, chain.Leaf(), key.Public(), chain.Keys[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	crt, err := x509.ParseCertificate(derBytes)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	keyDesc, err := attestation.Verify(append([]*x509.Certificate{crt}, chain.Certificates...), attestation.VerifyOptions{
+		Roots:       []*x509.Certificate{chain.Root()},
+		CurrentTime: now,
+	})
+	if !errors.Is(err, attestation.ErrInvalidChain) {
+		t.Errorf("Verify() = %+v, %v, wantErr %v", keyDesc, err, attestation.ErrInvalidChain)
+	}
+}
@@ -0,0 +1,151 @@
+package attestation
+
+import (
+	"encoding/binary"
+	"errors"
+	"math"
+)
+
+// CBOR major types, RFC 8949 section 3.1.
+const (
+	cborUnsignedInt = 0
+	cborNegativeInt = 1
+	cborByteString  = 2
+	cborTextString  = 3
+	cborArray       = 4
+	cborMap         = 5
+	cborTag         = 6
+	cborSimple      = 7
+)
+
+// cborMaxDepth limits the nesting of skipped CBOR items.
+const cborMaxDepth = 16
+
+var errMalformedCBOR = errors.New("malformed CBOR")
+
+// cborDecoder is a minimal decoder for definite-length CBOR data items.
+type cborDecoder struct {
+	data []byte
+}
+
+// readHeader reads the initial byte and argument of a data item.
+func (d *cborDecoder) readHeader() (major int, arg uint64, err error) {
+	if len(d.data) == 0 {
+		return 0, 0, errMalformedCBOR
+	}
+	b := d.data[0]
+	d.data = d.data[1:]
+
+	major, info := int(b>>5), b&0x1f
+	var n int
+	switch {
+	case info < 24:
+		return major, uint64(info), nil
+	case info == 24:
+		n = 1
+	case info == 25:
+		n = 2
+	case info == 26:
+		n = 4
+	case info == 27:
+		n = 8
+	default:
+		// Reserved values and indefinite lengths are not supported.
+		return 0, 0, errMalformedCBOR
+	}
+	if len(d.data) < n {
+		return 0, 0, errMalformedCBOR
+	}
+
+	var buf [8]byte
+	copy(buf[8-n:], d.data[:n])
+	d.data = d.data[n:]
+	return major, binary.BigEndian.Uint64(buf[:]), nil
+}
+
+// readInt reads an unsigned or negative integer.
+func (d *cborDecoder) readInt() (int64, error) {
+	major, arg, err := d.readHeader()
+	if err != nil {
+		return 0, err
+	}
+	if arg > math.MaxInt64 {
+		return 0, errMalformedCBOR
+	}
+	switch major {
+	case cborUnsignedInt:
+		return int64(arg), nil
+	case cborNegativeInt:
+		return -1 - int64(arg), nil
+	default:
+		return 0, errMalformedCBOR
+	}
+}
+
+// readString reads a byte string or a text string.
+func (d *cborDecoder) readString(wantMajor int) ([]byte, error) {
+	major, arg, err := d.readHeader()
+	if err != nil {
+		return nil, err
+	}
+	if major != wantMajor || arg > uint64(len(d.data)) {
+		return nil, errMalformedCBOR
+	}
+	s := d.data[:arg]
+	d.data = d.data[arg:]
+	return s, nil
+}
+
+// readMapHeader reads the header of a map and returns its number of pairs.
+func (d *cborDecoder) readMapHeader() (int, error) {
+	major, arg, err := d.readHeader()
+	if err != nil {
+		return 0, err
+	}
+	// Each pair takes at least two bytes.
+	if major != cborMap || arg > uint64(len(d.data)/2) {
+		return 0, errMalformedCBOR
+	}
+	return int(arg), nil
+}
+
+// skip skips a data item.
+func (d *cborDecoder) skip(depth int) error {
+	if depth > cborMaxDepth {
+		return errMalformedCBOR
+	}
+
+	major, arg, err := d.readHeader()
+	if err != nil {
+		return err
+	}
+
+	switch major {
+	case cborUnsignedInt, cborNegativeInt, cborSimple:
+		return nil
+	case cborByteString, cborTextString:
+		if arg > uint64(len(d.data)) {
+			return errMalformedCBOR
+		}
+		d.data = d.data[arg:]
+		return nil
+	case cborArray, cborMap:
+		if arg > uint64(len(d.data)) {
+			return errMalformedCBOR
+		}
+		n := int(arg)
+		if major == cborMap {
+			n *= 2
+		}
+		for i := 0; i < n; i++ {
+			if err := d.skip(depth + 1); err != nil {
+				return err
+			}
+		}
+		return nil
+	case cborTag:
+		return d.skip(depth + 1)
+	}
+
+	return errMalformedCBOR
+}
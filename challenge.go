@@ -0,0 +1,157 @@
+package attestation
+
+import (
+	"container/heap"
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"errors"
+	"sync"
+	"time"
+)
+
+const (
+	// DefaultChallengeTTL is the lifetime of challenges issued by a MemoryChallengeStore with no
+	// TTL.
+	DefaultChallengeTTL = 5 * time.Minute
+	// challengeSize is the length of issued challenges.
+	challengeSize = 32
+)
+
+// ErrInvalidChallenge is returned when an attestation challenge was not issued, has expired or
+// has already been used.
+var ErrInvalidChallenge = errors.New("attestation: invalid attestation challenge")
+
+// ChallengeStore issues attestation challenges and accepts each of them once.
+type ChallengeStore interface {
+	// Issue returns a new random challenge.
+	Issue(ctx context.Context) ([]byte, error)
+	// Consume reports whether challenge was issued and has neither expired nor been consumed.
+	// Once consumed, a challenge is no longer accepted.
+	Consume(ctx context.Context, challenge []byte) (bool, error)
+}
+
+// MemoryChallengeStore is an in-memory ChallengeStore safe for concurrent use. The zero value is
+// ready to use.
+type MemoryChallengeStore struct {
+	// TTL is the lifetime of issued challenges. If zero, DefaultChallengeTTL is used.
+	TTL time.Duration
+
+	now        func() time.Time
+	mu         sync.Mutex
+	challenges map[[sha256.Size]byte]issuedChallenge
+	expiries   expiryQueue
+}
+
+type issuedChallenge struct {
+	value   []byte
+	expires time.Time
+}
+
+// expiryQueue is a min-heap of issued challenges ordered by expiry, so that expired challenges
+// are pruned without scanning the whole store. Consumed challenges stay in the queue until they
+// expire.
+type expiryQueue []queuedChallenge
+
+type queuedChallenge struct {
+	key     [sha256.Size]byte
+	expires time.Time
+}
+
+func (q expiryQueue) Len() int           { return len(q) }
+func (q expiryQueue) Less(i, j int) bool { return q[i].expires.Before(q[j].expires) }
+func (q expiryQueue) Swap(i, j int)      { q[i], q[j] = q[j], q[i] }
+func (q *expiryQueue) Push(x any)        { *q = append(*q, x.(queuedChallenge)) }
+func (q *expiryQueue) Pop() any {
+	old := *q
+	x := old[len(old)-1]
+	*q = old[:len(old)-1]
+	return x
+}
+
+// Issue implements the ChallengeStore interface.
+func (s *MemoryChallengeStore) Issue(ctx context.Context) ([]byte, error) {
+	challenge := make([]byte, challengeSize)
+	if _, err := rand.Read(challenge); err != nil {
+		return nil, err
+	}
+
+	ttl := s.TTL
+	if ttl == 0 {
+		ttl = DefaultChallengeTTL
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := s.currentTime()
+	if s.challenges == nil {
+		s.challenges = make(map[[sha256.Size]byte]issuedChallenge)
+	}
+	for len(s.expiries) > 0 && !now.Before(s.expiries[0].expires) {
+		q := heap.Pop(&s.expiries).(queuedChallenge)
+		if c, ok := s.challenges[q.key]; ok && c.expires.Equal(q.expires) {
+			delete(s.challenges, q.key)
+		}
+	}
+	key, expires := sha256.Sum256(challenge), now.Add(ttl)
+	s.challenges[key] = issuedChallenge{value: challenge, expires: expires}
+	heap.Push(&s.expiries, queuedChallenge{key: key, expires: expires})
+
+	return challenge, nil
+}
+
+// Consume implements the ChallengeStore interface.
+//
+// Challenges are looked up by digest and compared in constant time.
+func (s *MemoryChallengeStore) Consume(ctx context.Context, challenge []byte) (bool, error) {
+	key := sha256.Sum256(challenge)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	c, ok := s.challenges[key]
+	if !ok {
+		return false, nil
+	}
+	delete(s.challenges, key)
+
+	if subtle.ConstantTimeCompare(c.value, challenge) != 1 {
+		return false, nil
+	}
+	return s.currentTime().Before(c.expires), nil
+}
+
+func (s *MemoryChallengeStore) currentTime() time.Time {
+	if s.now != nil {
+		return s.now()
+	}
+	return time.Now()
+}
+
+// VerifyChallenge checks that the attestation challenge of keyDesc was issued by store and
+// consumes it. It returns ErrInvalidChallenge if the challenge is not accepted.
+func VerifyChallenge(ctx context.Context, store ChallengeStore, keyDesc *KeyDescription) error {
+	if len(keyDesc.AttestationChallenge) == 0 {
+		return ErrInvalidChallenge
+	}
+
+	ok, err := store.Consume(ctx, keyDesc.AttestationChallenge)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return ErrInvalidChallenge
+	}
+	return nil
+}
+
+// CheckChallenge checks in constant time that the attestation challenge of keyDesc is
+// challenge. It returns ErrInvalidChallenge otherwise.
+func CheckChallenge(keyDesc *KeyDescription, challenge []byte) error {
+	if len(challenge) == 0 || subtle.ConstantTimeCompare(keyDesc.AttestationChallenge, challenge) != 1 {
+		return ErrInvalidChallenge
+	}
+	return nil
+}
@@ -0,0 +1,151 @@
+package attestation
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestMemoryChallengeStore(t *testing.T) {
+	ctx := context.Background()
+	now := time.Date(2024, time.January, 1, 0, 0, 0, 0, time.UTC)
+	store := &MemoryChallengeStore{TTL: time.Minute, now: func() time.Time { return now }}
+
+	challenge, err := store.Issue(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(challenge) != challengeSize {
+		t.Errorf("Issue() = %d bytes, want %d", len(challenge), challengeSize)
+	}
+
+	if ok, err := store.Consume(ctx, []byte("unknown")); ok || err != nil {
+		t.Errorf("Consume() = %v, %v, want false", ok, err)
+	}
+	if ok, err := store.Consume(ctx, challenge); !ok || err != nil {
+		t.Errorf("Consume() = %v, %v, want true", ok, err)
+	}
+	if ok, err := store.Consume(ctx, challenge); ok || err != nil {
+		t.Errorf("Consume() = %v, %v, want false when reused", ok, err)
+	}
+
+	expired, err := store.Issue(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	now = now.Add(time.Minute)
+	if ok, err := store.Consume(ctx, expired); ok || err != nil {
+		t.Errorf("Consume() = %v, %v, want false when expired", ok, err)
+	}
+}
+
+// TestMemoryChallengeStore_prune checks that Issue only drops expired challenges, consumed or not.
+func TestMemoryChallengeStore_prune(t *testing.T) {
+	ctx := context.Background()
+	now := time.Date(2024, time.January, 1, 0, 0, 0, 0, time.UTC)
+	store := &MemoryChallengeStore{TTL: time.Minute, now: func() time.Time { return now }}
+
+	consumed, err := store.Issue(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := store.Issue(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if ok, err := store.Consume(ctx, consumed); !ok || err != nil {
+		t.Fatalf("Consume() = %v, %v, want true", ok, err)
+	}
+
+	now = now.Add(30 * time.Second)
+	pending, err := store.Issue(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	now = now.Add(30 * time.Second)
+	if _, err := store.Issue(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if len(store.challenges) != 2 || len(store.expiries) != 2 {
+		t.Errorf("store holds %d challenges and %d expiries, want 2", len(store.challenges), len(store.expiries))
+	}
+	if ok, err := store.Consume(ctx, pending); !ok || err != nil {
+		t.Errorf("Consume() = %v, %v, want true", ok, err)
+	}
+}
+
+func TestMemoryChallengeStore_concurrent(t *testing.T) {
+	ctx := context.Background()
+	store := &MemoryChallengeStore{}
+
+	challenge, err := store.Issue(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	accepted := 0
+	for i := 0; i < 16; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if _, err := store.Issue(ctx); err != nil {
+				t.Error(err)
+			}
+			if ok, _ := store.Consume(ctx, challenge); ok {
+				mu.Lock()
+				accepted++
+				mu.Unlock()
+			}
+		}()
+	}
+	wg.Wait()
+
+	if accepted != 1 {
+		t.Errorf("Consume() accepted %d times, want 1", accepted)
+	}
+}
+
+func TestVerifyChallenge(t *testing.T) {
+	ctx := context.Background()
+	store := &MemoryChallengeStore{}
+
+	challenge, err := store.Issue(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name    string
+		keyDesc *KeyDescription
+		wantErr error
+	}{
+		{name: "shouldFailWhenEmpty", keyDesc: &KeyDescription{}, wantErr: ErrInvalidChallenge},
+		{name: "shouldFailWhenUnknown", keyDesc: &KeyDescription{AttestationChallenge: []byte("x")}, wantErr: ErrInvalidChallenge},
+		{name: "shouldSucceedWhenIssued", keyDesc: &KeyDescription{AttestationChallenge: challenge}},
+		{name: "shouldFailWhenReused", keyDesc: &KeyDescription{AttestationChallenge: challenge}, wantErr: ErrInvalidChallenge},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := VerifyChallenge(ctx, store, tt.keyDesc); !errors.Is(err, tt.wantErr) {
+				t.Errorf("VerifyChallenge() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestCheckChallenge(t *testing.T) {
+	keyDesc := &KeyDescription{AttestationChallenge: []byte("challenge")}
+	if err := CheckChallenge(keyDesc, []byte("challenge")); err != nil {
+		t.Errorf("CheckChallenge() error = %v, want nil", err)
+	}
+	if err := CheckChallenge(keyDesc, []byte("other")); !errors.Is(err, ErrInvalidChallenge) {
+		t.Errorf("CheckChallenge() error = %v, want %v", err, ErrInvalidChallenge)
+	}
+	if err := CheckChallenge(&KeyDescription{}, nil); !errors.Is(err, ErrInvalidChallenge) {
+		t.Errorf("CheckChallenge() error = %v, want %v", err, ErrInvalidChallenge)
+	}
+}
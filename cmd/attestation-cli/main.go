@@ -1,16 +1,27 @@
 package main
 
 import (
+	"bytes"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
 	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/hex"
 	"encoding/json"
 	"encoding/pem"
 	"errors"
 	"flag"
 	"fmt"
 	"io"
+	"math/big"
 	"os"
 	"reflect"
 	"strings"
+	"time"
 
 	"github.com/mbreban/attestation"
 	"github.com/mbreban/attestation/cmd/attestation-cli/version"
@@ -46,6 +57,26 @@ func (f *Format) Get() any { return string(*f) }
 
 func (f *Format) String() string { return fmt.Sprintf("%q", *f) }
 
+type OutputFormat string
+
+func (f *OutputFormat) Set(val string) error {
+	val = strings.ToUpper(val)
+
+	switch val {
+	case "DER", "PEM", "CERT":
+	default:
+		return errors.New("DER, PEM or CERT expected")
+	}
+
+	*f = OutputFormat(val)
+
+	return nil
+}
+
+func (f *OutputFormat) Get() any { return string(*f) }
+
+func (f *OutputFormat) String() string { return fmt.Sprintf("%q", *f) }
+
 type printer struct {
 	w      io.StringWriter
 	prefix string
@@ -69,8 +100,12 @@ func usage() {
 	fmt.Fprintf(flag.CommandLine.Output(), "Usage of %s:\n", os.Args[0])
 	fmt.Fprintf(flag.CommandLine.Output(), "  attestation-cli [command]\n")
 	fmt.Fprintf(flag.CommandLine.Output(), "\nAvailable commands:\n")
+	fmt.Fprintf(flag.CommandLine.Output(), "  chain       Print an attestation certificate chain and the key attestation extension of its leaf\n")
+	fmt.Fprintf(flag.CommandLine.Output(), "  create      Create a key attestation extension or test certificate from a JSON template\n")
 	fmt.Fprintf(flag.CommandLine.Output(), "  help        Show this help\n")
 	fmt.Fprintf(flag.CommandLine.Output(), "  parse       Parse the key attestation extension contained in an X.509 certificate if present\n")
+	fmt.Fprintf(flag.CommandLine.Output(), "  policy      Evaluate a verification policy against the key attestation extension\n")
+	fmt.Fprintf(flag.CommandLine.Output(), "  verify      Verify an attestation certificate chain\n")
 	fmt.Fprintf(flag.CommandLine.Output(), "  version     Print the version number\n")
 }
 
@@ -78,6 +113,15 @@ func main() {
 	var format = Format("PEM")
 	var jsonEncoded bool
 	var out string
+	var policyFile string
+	var rootsFile string
+	var revocationsFile string
+	var challenge string
+	var templateFile string
+	var outputFormat = OutputFormat("DER")
+	var issuerFile string
+	var issuerKeyFile string
+	var keyOut string
 
 	parseCmd := flag.NewFlagSet("parse", flag.ExitOnError)
 	parseCmd.Var(&format, "format", "X.509 certificate format (one of PEM or DER)")
@@ -90,6 +134,63 @@ func main() {
 		parseCmd.PrintDefaults()
 	}
 
+	policyCmd := flag.NewFlagSet("policy", flag.ExitOnError)
+	policyCmd.Var(&format, "format", "X.509 certificate format (one of PEM or DER)")
+	policyCmd.BoolVar(&jsonEncoded, "json", false, "Encode output in JSON format")
+	policyCmd.StringVar(&policyFile, "policy", "", "Policy file (JSON)")
+	policyCmd.Usage = func() {
+		fmt.Fprintf(policyCmd.Output(), "Usage of %s:\n", policyCmd.Name())
+		fmt.Fprintf(policyCmd.Output(), "  attestation-cli  %s -policy file [flag]... [file]...\n", policyCmd.Name())
+		fmt.Fprintf(policyCmd.Output(), "\nFlags:\n")
+		policyCmd.PrintDefaults()
+	}
+
+	chainCmd := flag.NewFlagSet("chain", flag.ExitOnError)
+	chainCmd.Var(&format, "format", "X.509 certificate format (one of PEM or DER)")
+	chainCmd.BoolVar(&jsonEncoded, "json", false, "Encode output in JSON format")
+	chainCmd.Usage = func() {
+		fmt.Fprintf(chainCmd.Output(), "Usage of %s:\n", chainCmd.Name())
+		fmt.Fprintf(chainCmd.Output(), "  attestation-cli  %s [flag]... [file]...\n", chainCmd.Name())
+		fmt.Fprintf(chainCmd.Output(), "\nFlags:\n")
+		chainCmd.PrintDefaults()
+	}
+
+	verifyCmd := flag.NewFlagSet("verify", flag.ExitOnError)
+	verifyCmd.Var(&format, "format", "X.509 certificate format (one of PEM or DER)")
+	verifyCmd.BoolVar(&jsonEncoded, "json", false, "Encode output in JSON format")
+	verifyCmd.StringVar(&rootsFile, "roots", "", "Additional trusted root certificates file")
+	verifyCmd.StringVar(&revocationsFile, "revocations", "", "Revocation status list file (JSON)")
+	verifyCmd.StringVar(&challenge, "challenge", "", "Expected attestation challenge (hex)")
+	verifyCmd.StringVar(&policyFile, "policy", "", "Policy file (JSON)")
+	verifyCmd.Usage = func() {
+		fmt.Fprintf(verifyCmd.Output(), "Usage of %s:\n", verifyCmd.Name())
+		fmt.Fprintf(verifyCmd.Output(), "  attestation-cli  %s [flag]... [file]...\n", verifyCmd.Name())
+		fmt.Fprintf(verifyCmd.Output(), "\nFlags:\n")
+		verifyCmd.PrintDefaults()
+		fmt.Fprintf(verifyCmd.Output(), "\nExit status:\n")
+		fmt.Fprintf(verifyCmd.Output(), "  %d  verification passed\n", exitOK)
+		fmt.Fprintf(verifyCmd.Output(), "  %d  usage or input error\n", exitError)
+		fmt.Fprintf(verifyCmd.Output(), "  %d  invalid, expired or untrusted certificate chain\n", exitInvalidChain)
+		fmt.Fprintf(verifyCmd.Output(), "  %d  revoked or suspended certificate\n", exitRevoked)
+		fmt.Fprintf(verifyCmd.Output(), "  %d  missing or invalid key attestation extension\n", exitInvalidExtension)
+		fmt.Fprintf(verifyCmd.Output(), "  %d  attestation challenge mismatch\n", exitChallengeMismatch)
+		fmt.Fprintf(verifyCmd.Output(), "  %d  policy evaluation failed\n", exitPolicyFailed)
+	}
+
+	createCmd := flag.NewFlagSet("create", flag.ExitOnError)
+	createCmd.StringVar(&templateFile, "template", "", "KeyDescription template file (JSON)")
+	createCmd.Var(&outputFormat, "output", "Output format (one of DER, PEM or CERT)")
+	createCmd.StringVar(&out, "out", "", "Output file")
+	createCmd.StringVar(&issuerFile, "issuer", "", "Issuer certificate file (PEM), the certificate is self-signed if empty")
+	createCmd.StringVar(&issuerKeyFile, "issuer-key", "", "Issuer private key file (PEM)")
+	createCmd.StringVar(&keyOut, "key-out", "", "Output file of the certificate private key (PEM)")
+	createCmd.Usage = func() {
+		fmt.Fprintf(createCmd.Output(), "Usage of %s:\n", createCmd.Name())
+		fmt.Fprintf(createCmd.Output(), "  attestation-cli  %s -template file [flag]...\n", createCmd.Name())
+		fmt.Fprintf(createCmd.Output(), "\nFlags:\n")
+		createCmd.PrintDefaults()
+	}
+
 	if len(os.Args) < 2 {
 		usage()
 		os.Exit(1)
@@ -106,6 +207,50 @@ func main() {
 		}
 
 		parse(parseCmd.Args(), format, jsonEncoded, out)
+	case "policy":
+		if err := policyCmd.Parse(os.Args[2:]); err != nil {
+			fatalln(err)
+		}
+
+		if policyFile == "" || policyCmd.NArg() < 1 {
+			policyCmd.Usage()
+			os.Exit(1)
+		}
+
+		evaluatePolicy(policyCmd.Args(), format, jsonEncoded, policyFile)
+	case "chain":
+		if err := chainCmd.Parse(os.Args[2:]); err != nil {
+			fatalln(err)
+		}
+
+		if chainCmd.NArg() < 1 {
+			chainCmd.Usage()
+			os.Exit(1)
+		}
+
+		printChain(chainCmd.Args(), format, jsonEncoded)
+	case "verify":
+		if err := verifyCmd.Parse(os.Args[2:]); err != nil {
+			fatalln(err)
+		}
+
+		if verifyCmd.NArg() < 1 {
+			verifyCmd.Usage()
+			os.Exit(exitError)
+		}
+
+		verify(verifyCmd.Args(), format, jsonEncoded, rootsFile, revocationsFile, challenge, policyFile)
+	case "create":
+		if err := createCmd.Parse(os.Args[2:]); err != nil {
+			fatalln(err)
+		}
+
+		if templateFile == "" || (issuerFile == "") != (issuerKeyFile == "") {
+			createCmd.Usage()
+			os.Exit(1)
+		}
+
+		create(templateFile, outputFormat, out, issuerFile, issuerKeyFile, keyOut)
 	case "version":
 		printVersion()
 	case "help":
@@ -136,67 +281,790 @@ func parse(names []string, format Format, jsonEncoded bool, out string) {
 		output = f
 	}
 
+	// JSON output is a single array so that it can be read back, e.g. by create.
+	var entries []parsedCertificate
 	for _, name := range names {
-		bytes, err := os.ReadFile(name)
+		crts := readCertificates(name, format)
+
+		found := false
+		for i, crt := range crts {
+			var keyDesc *attestation.KeyDescription
+			var provInfo *attestation.ProvisioningInfo
+
+			// Intermediates and roots of a chain carry neither extension.
+			ext := attestation.GetKeyExtension(crt)
+			provExt := attestation.GetProvisioningInfoExtension(crt)
+			if ext == nil && provExt == nil {
+				continue
+			}
+			found = true
+
+			if ext != nil {
+				var err error
+				keyDesc, err = attestation.ParseExtension(ext.Value)
+				if err != nil {
+					fatalln(err)
+				}
+			}
+
+			if provExt != nil {
+				var err error
+				provInfo, err = attestation.ParseProvisioningInfo(provExt.Value)
+				if err != nil {
+					fatalln(err)
+				}
+			}
+
+			if jsonEncoded {
+				entries = append(entries, parsedCertificate{
+					Name:             name,
+					Index:            i,
+					Subject:          crt.Subject.String(),
+					KeyDescription:   keyDesc,
+					ProvisioningInfo: provInfo,
+				})
+			} else {
+				printer := &printer{
+					w:      output,
+					prefix: "",
+					indent: "  ",
+				}
+
+				printer.Printf("%s / %d / %q\n", name, i, crt.Subject.String())
+				if keyDesc != nil {
+					printKeyDescription(printer, keyDesc)
+				}
+				if provInfo != nil {
+					printer.Printf("ProvisioningInfo:\n")
+					printProvisioningInfo(printer, provInfo)
+				}
+			}
+		}
+
+		if !found {
+			fatalf("failed to get key extension (OID: %s) in %s\n", attestation.OIDKeyAttestationExtension.String(), name)
+		}
+	}
+
+	if jsonEncoded {
+		raw, err := json.MarshalIndent(entries, "", "  ")
 		if err != nil {
 			fatalln(err)
 		}
 
-		var crts []*x509.Certificate
-		switch format.Get() {
-		case "PEM":
-			crts = parseCertsFromPEM(bytes)
-		case "DER":
-			crts, err = x509.ParseCertificates(bytes)
-			if err != nil {
-				fatalln(err)
-			}
+		_, err = output.WriteString(string(raw) + "\n")
+		if err != nil {
+			fatalln(err)
 		}
+	}
+}
 
-		for i, crt := range crts {
+// parsedCertificate is the JSON output of parse for a certificate.
+type parsedCertificate struct {
+	Name             string
+	Index            int
+	Subject          string
+	KeyDescription   *attestation.KeyDescription   `json:",omitempty"`
+	ProvisioningInfo *attestation.ProvisioningInfo `json:",omitempty"`
+}
+
+// chainCertificate describes a certificate of an attestation chain.
+type chainCertificate struct {
+	Index            int
+	Role             string
+	Subject          string
+	Issuer           string
+	SerialNumber     string
+	NotBefore        time.Time
+	NotAfter         time.Time
+	KeyDescription   *attestation.KeyDescription   `json:",omitempty"`
+	ProvisioningInfo *attestation.ProvisioningInfo `json:",omitempty"`
+}
+
+func printChain(names []string, format Format, jsonEncoded bool) {
+	var crts []*x509.Certificate
+	for _, name := range names {
+		crts = append(crts, readCertificates(name, format)...)
+	}
+	if len(crts) == 0 {
+		fatalln("no certificate found")
+	}
+
+	chain := orderChain(crts)
+
+	var certs []chainCertificate
+	for i, crt := range chain {
+		c := chainCertificate{
+			Index:        i,
+			Role:         chainRole(chain, i),
+			Subject:      crt.Subject.String(),
+			Issuer:       crt.Issuer.String(),
+			SerialNumber: fmt.Sprintf("%x", crt.SerialNumber),
+			NotBefore:    crt.NotBefore,
+			NotAfter:     crt.NotAfter,
+		}
+
+		if i == 0 {
 			ext := attestation.GetKeyExtension(crt)
 			if ext == nil {
-				fatalf("failed to get key extension (OID: %s) in %s\n", attestation.OIDKeyAttestationExtension.String(), name)
+				fatalf("failed to get key extension (OID: %s) in leaf certificate\n", attestation.OIDKeyAttestationExtension.String())
 			}
 
 			keyDesc, err := attestation.ParseExtension(ext.Value)
 			if err != nil {
 				fatalln(err)
 			}
+			c.KeyDescription = keyDesc
+		}
 
-			if jsonEncoded {
-				data := struct {
-					Name           string
-					Index          int
-					Subject        string
-					KeyDescription *attestation.KeyDescription
-				}{
-					Name:           name,
-					Index:          i,
-					Subject:        crt.Subject.String(),
-					KeyDescription: keyDesc,
-				}
+		if ext := attestation.GetProvisioningInfoExtension(crt); ext != nil {
+			info, err := attestation.ParseProvisioningInfo(ext.Value)
+			if err != nil {
+				fatalln(err)
+			}
+			c.ProvisioningInfo = info
+		}
 
-				raw, err := json.MarshalIndent(data, "", "  ")
-				if err != nil {
-					fatalln(err)
-				}
+		certs = append(certs, c)
+	}
 
-				_, err = output.WriteString(string(raw))
-				if err != nil {
-					fatalln(err)
-				}
+	knownRoot := attestation.IsGoogleRoot(chain[len(chain)-1])
+
+	if jsonEncoded {
+		data := struct {
+			Certificates []chainCertificate
+			KnownRoot    bool
+		}{
+			Certificates: certs,
+			KnownRoot:    knownRoot,
+		}
+
+		raw, err := json.MarshalIndent(data, "", "  ")
+		if err != nil {
+			fatalln(err)
+		}
+		fmt.Println(string(raw))
+		return
+	}
+
+	printer := &printer{
+		w:      os.Stdout,
+		prefix: "",
+		indent: "  ",
+	}
+
+	for _, c := range certs {
+		printer.Printf("%d / %s / %q\n", c.Index, c.Role, c.Subject)
+		printChainCertificate(printer, c)
+	}
+
+	if knownRoot {
+		printer.Printf("Root: known Google attestation root\n")
+	} else {
+		printer.Printf("Root: unknown\n")
+	}
+}
+
+func printChainCertificate(printer *printer, c chainCertificate) {
+	printer.Outdent()
+	defer printer.Indent()
+
+	printer.Printf("Issuer: %q\n", c.Issuer)
+	printer.Printf("SerialNumber: %s\n", c.SerialNumber)
+	printer.Printf("NotBefore: %s\n", c.NotBefore.Format(time.RFC3339))
+	printer.Printf("NotAfter: %s\n", c.NotAfter.Format(time.RFC3339))
+
+	if c.KeyDescription != nil {
+		printer.Printf("KeyDescription:\n")
+		printer.Outdent()
+		printKeyDescription(printer, c.KeyDescription)
+		printer.Indent()
+	}
+	if c.ProvisioningInfo != nil {
+		printer.Printf("ProvisioningInfo:\n")
+		printProvisioningInfo(printer, c.ProvisioningInfo)
+	}
+}
+
+// orderChain orders certificates from the leaf to the root by following issuer names. The leaf is
+// the only certificate that issued none of the others. Certificates are returned in their original
+// order when no such ordering exists.
+func orderChain(crts []*x509.Certificate) []*x509.Certificate {
+	isIssuer := func(parent, crt *x509.Certificate) bool {
+		return parent != crt && bytes.Equal(crt.RawIssuer, parent.RawSubject)
+	}
+
+	var leaf *x509.Certificate
+	for _, crt := range crts {
+		issued := false
+		for _, other := range crts {
+			if isIssuer(crt, other) {
+				issued = true
+				break
+			}
+		}
+		if !issued {
+			if leaf != nil {
+				return crts
+			}
+			leaf = crt
+		}
+	}
+	if leaf == nil {
+		return crts
+	}
+
+	chain := []*x509.Certificate{leaf}
+	for len(chain) < len(crts) {
+		var next *x509.Certificate
+		for _, crt := range crts {
+			if isIssuer(crt, chain[len(chain)-1]) {
+				next = crt
+				break
+			}
+		}
+		if next == nil {
+			return crts
+		}
+		chain = append(chain, next)
+	}
+
+	return chain
+}
+
+// chainRole returns the role of the i-th certificate of an ordered chain.
+func chainRole(chain []*x509.Certificate, i int) string {
+	switch {
+	case i == 0:
+		return "leaf"
+	case i == len(chain)-1 && bytes.Equal(chain[i].RawIssuer, chain[i].RawSubject):
+		return "root"
+	default:
+		return "intermediate"
+	}
+}
+
+// Exit status of the verify command, one per failure class.
+const (
+	exitOK = iota
+	exitError
+	exitInvalidChain
+	exitRevoked
+	exitInvalidExtension
+	exitChallengeMismatch
+	exitPolicyFailed
+)
+
+// verifyCheck is the outcome of a verification step.
+type verifyCheck struct {
+	Name   string
+	Status string
+	Reason string `json:",omitempty"`
+}
+
+func verify(names []string, format Format, jsonEncoded bool, rootsFile, revocationsFile, challenge, policyFile string) {
+	var opts attestation.VerifyOptions
+
+	if rootsFile != "" {
+		opts.Roots = readCertificates(rootsFile, format)
+	}
+
+	if revocationsFile != "" {
+		f, err := os.Open(revocationsFile)
+		if err != nil {
+			fatalln(err)
+		}
+		defer f.Close()
+
+		opts.Revocations, err = attestation.ParseRevocationList(f)
+		if err != nil {
+			fatalln(err)
+		}
+	}
+
+	var wantChallenge []byte
+	if challenge != "" {
+		var err error
+		wantChallenge, err = hex.DecodeString(challenge)
+		if err != nil {
+			fatalf("invalid challenge: %v\n", err)
+		}
+	}
+
+	var policy *attestation.Policy
+	if policyFile != "" {
+		f, err := os.Open(policyFile)
+		if err != nil {
+			fatalln(err)
+		}
+		defer f.Close()
+
+		policy, err = attestation.ParsePolicy(f)
+		if err != nil {
+			fatalln(err)
+		}
+	}
+
+	var crts []*x509.Certificate
+	for _, name := range names {
+		crts = append(crts, readCertificates(name, format)...)
+	}
+
+	report, code := runVerify(orderChain(crts), opts, wantChallenge, policy)
+
+	if jsonEncoded {
+		raw, err := json.MarshalIndent(report, "", "  ")
+		if err != nil {
+			fatalln(err)
+		}
+		fmt.Println(string(raw))
+	} else {
+		printer := &printer{
+			w:      os.Stdout,
+			prefix: "",
+			indent: "  ",
+		}
+
+		if report.Passed {
+			printer.Printf("Verification: PASS\n")
+		} else {
+			printer.Printf("Verification: FAIL\n")
+		}
+
+		printer.Outdent()
+		for _, c := range report.Checks {
+			if c.Reason != "" {
+				printer.Printf("[%s] %s: %s\n", c.Status, c.Name, c.Reason)
+			} else {
+				printer.Printf("[%s] %s\n", c.Status, c.Name)
+			}
+		}
+		if report.Policy != nil {
+			printRuleResults(printer, report.Policy.Results)
+		}
+		printer.Indent()
+	}
+
+	os.Exit(code)
+}
+
+// verifyReport is the outcome of the verify command.
+type verifyReport struct {
+	Passed         bool
+	Checks         []verifyCheck
+	KeyDescription *attestation.KeyDescription `json:",omitempty"`
+	Policy         *attestation.PolicyResult   `json:",omitempty"`
+}
+
+// runVerify verifies the ordered chain crts, then checks its challenge and evaluates policy when
+// given. It returns the outcome of each step and the exit status of the first failure.
+func runVerify(crts []*x509.Certificate, opts attestation.VerifyOptions, wantChallenge []byte, policy *attestation.Policy) (verifyReport, int) {
+	var report verifyReport
+	code := exitOK
+
+	// check records the outcome of a step. Steps following a failure are skipped.
+	check := func(name string, run bool, f func() (int, error)) {
+		switch {
+		case code != exitOK:
+			report.Checks = append(report.Checks, verifyCheck{Name: name, Status: "SKIP"})
+		case !run:
+			report.Checks = append(report.Checks, verifyCheck{Name: name, Status: "SKIP", Reason: "not requested"})
+		default:
+			if failCode, err := f(); err != nil {
+				report.Checks = append(report.Checks, verifyCheck{Name: name, Status: "FAIL", Reason: err.Error()})
+				code = failCode
 			} else {
+				report.Checks = append(report.Checks, verifyCheck{Name: name, Status: "PASS"})
+			}
+		}
+	}
+
+	check("chain", true, func() (int, error) {
+		var err error
+		report.KeyDescription, err = attestation.Verify(crts, opts)
+		if err != nil {
+			return verifyExitCode(err), err
+		}
+		return exitOK, nil
+	})
+	check("challenge", wantChallenge != nil, func() (int, error) {
+		return exitChallengeMismatch, attestation.CheckChallenge(report.KeyDescription, wantChallenge)
+	})
+	check("policy", policy != nil, func() (int, error) {
+		result := policy.Evaluate(report.KeyDescription)
+		report.Policy = &result
+		if !result.Passed {
+			return exitPolicyFailed, fmt.Errorf("%d rule(s) failed", len(result.Failed()))
+		}
+		return exitOK, nil
+	})
+
+	report.Passed = code == exitOK
+	return report, code
+}
+
+// verifyExitCode returns the exit status matching an error returned by attestation.Verify.
+func verifyExitCode(err error) int {
+	switch {
+	case errors.Is(err, attestation.ErrRevoked):
+		return exitRevoked
+	case errors.Is(err, attestation.ErrEmptyChain),
+		errors.Is(err, attestation.ErrInvalidChain),
+		errors.Is(err, attestation.ErrCertificateExpired),
+		errors.Is(err, attestation.ErrUntrustedRoot):
+		return exitInvalidChain
+	default:
+		return exitInvalidExtension
+	}
+}
+
+func create(templateFile string, outputFormat OutputFormat, out, issuerFile, issuerKeyFile, keyOut string) {
+	template := readTemplate(templateFile)
+
+	var output []byte
+	switch outputFormat.Get() {
+	case "DER":
+		derBytes, err := attestation.CreateKeyDescription(template)
+		if err != nil {
+			fatalln(err)
+		}
+		output = derBytes
+	case "PEM":
+		derBytes, err := attestation.CreateKeyDescription(template)
+		if err != nil {
+			fatalln(err)
+		}
+		output = pem.EncodeToMemory(&pem.Block{Type: "KEY ATTESTATION EXTENSION", Bytes: derBytes})
+	case "CERT":
+		output = createCertificate(template, issuerFile, issuerKeyFile, keyOut)
+	}
+
+	if out == "" {
+		if _, err := os.Stdout.Write(output); err != nil {
+			fatalln(err)
+		}
+		return
+	}
+
+	if err := os.WriteFile(out, output, 0o644); err != nil {
+		fatalln(err)
+	}
+}
+
+// readTemplate reads a KeyDescription template file.
+func readTemplate(name string) *attestation.KeyDescription {
+	data, err := os.ReadFile(name)
+	if err != nil {
+		fatalln(err)
+	}
+
+	template, err := parseTemplate(data)
+	if err != nil {
+		fatalf("%v in %s\n", err, name)
+	}
+	return template
+}
+
+// parseTemplate parses a KeyDescription template. The template is either a KeyDescription, an
+// object holding it in a KeyDescription field, or the array emitted by parse -json.
+func parseTemplate(data []byte) (*attestation.KeyDescription, error) {
+	// The output of parse -json is an array, whose first KeyDescription is the one of the leaf.
+	if trimmed := bytes.TrimSpace(data); len(trimmed) > 0 && trimmed[0] == '[' {
+		var entries []parsedCertificate
+		if err := json.Unmarshal(trimmed, &entries); err != nil {
+			return nil, err
+		}
+		for _, entry := range entries {
+			if entry.KeyDescription != nil {
+				return entry.KeyDescription, nil
+			}
+		}
+		return nil, errors.New("no KeyDescription found")
+	}
+
+	var wrapper struct {
+		KeyDescription *attestation.KeyDescription
+	}
+	if err := json.Unmarshal(data, &wrapper); err != nil {
+		return nil, err
+	}
+	if wrapper.KeyDescription != nil {
+		return wrapper.KeyDescription, nil
+	}
+
+	var template attestation.KeyDescription
+	if err := json.Unmarshal(data, &template); err != nil {
+		return nil, err
+	}
+
+	return &template, nil
+}
+
+// createCertificate creates a PEM encoded test certificate carrying the key attestation extension.
+// The certificate key matches the Algorithm, KeySize and EcCurve of the template.
+func createCertificate(template *attestation.KeyDescription, issuerFile, issuerKeyFile, keyOut string) []byte {
+	ext, err := attestation.CreateExtension(template)
+	if err != nil {
+		fatalln(err)
+	}
+
+	key, err := generateKey(template)
+	if err != nil {
+		fatalln(err)
+	}
+
+	crt := &x509.Certificate{
+		SerialNumber:    big.NewInt(1),
+		Subject:         pkix.Name{CommonName: "Android Keystore Key"},
+		NotBefore:       time.Date(1970, 1, 1, 0, 0, 0, 0, time.UTC),
+		NotAfter:        time.Date(2048, 1, 1, 0, 0, 0, 0, time.UTC),
+		KeyUsage:        x509.KeyUsageDigitalSignature,
+		ExtraExtensions: []pkix.Extension{*ext},
+	}
+
+	parent, parentKey := crt, crypto.Signer(key)
+	if issuerFile != "" {
+		crts := readCertificates(issuerFile, "PEM")
+		if len(crts) == 0 {
+			fatalf("no certificate found in %s\n", issuerFile)
+		}
+		parent = crts[0]
+		parentKey = readPrivateKey(issuerKeyFile)
+	}
+
+	derBytes, err := x509.CreateCertificate(rand.Reader, crt, parent, key.Public(), parentKey)
+	if err != nil {
+		fatalln(err)
+	}
+
+	if keyOut != "" {
+		keyBytes, err := x509.MarshalPKCS8PrivateKey(key)
+		if err != nil {
+			fatalln(err)
+		}
+		if err := os.WriteFile(keyOut, pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: keyBytes}), 0o600); err != nil {
+			fatalln(err)
+		}
+	}
+
+	return pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: derBytes})
+}
+
+// generateKey generates a key matching the Algorithm, KeySize and EcCurve of the template. An EC
+// key is generated by default, on the curve given by EcCurve, or by KeySize when EcCurve is absent,
+// and P-256 when both are absent.
+func generateKey(template *attestation.KeyDescription) (crypto.Signer, error) {
+	algorithm := template.TeeEnforced.Algorithm
+	if algorithm == nil {
+		algorithm = template.SoftwareEnforced.Algorithm
+	}
+	keySize := template.TeeEnforced.KeySize
+	if keySize == nil {
+		keySize = template.SoftwareEnforced.KeySize
+	}
+	ecCurve := template.TeeEnforced.EcCurve
+	if ecCurve == nil {
+		ecCurve = template.SoftwareEnforced.EcCurve
+	}
+
+	if algorithm != nil && *algorithm == attestation.AlgoRSA {
+		bits := 2048
+		if keySize != nil {
+			bits = *keySize
+		}
+		return rsa.GenerateKey(rand.Reader, bits)
+	}
+	if algorithm != nil && *algorithm != attestation.AlgoEC {
+		return nil, fmt.Errorf("unsupported algorithm %v", *algorithm)
+	}
+
+	if ecCurve != nil && *ecCurve == attestation.Curve25519 {
+		_, key, err := ed25519.GenerateKey(rand.Reader)
+		return key, err
+	}
+
+	curves := map[attestation.EcCurve]elliptic.Curve{
+		attestation.CurveP224: elliptic.P224(),
+		attestation.CurveP256: elliptic.P256(),
+		attestation.CurveP384: elliptic.P384(),
+		attestation.CurveP521: elliptic.P521(),
+	}
+
+	var curve elliptic.Curve
+	switch {
+	case ecCurve != nil:
+		curve = curves[*ecCurve]
+		if curve == nil {
+			return nil, fmt.Errorf("unsupported EC curve %v", *ecCurve)
+		}
+	case keySize != nil:
+		for _, c := range curves {
+			if c.Params().BitSize == *keySize {
+				curve = c
+			}
+		}
+		if curve == nil {
+			return nil, fmt.Errorf("unsupported EC key size %d", *keySize)
+		}
+	default:
+		curve = elliptic.P256()
+	}
+	if keySize != nil && *keySize != curve.Params().BitSize {
+		return nil, fmt.Errorf("EC key size %d does not match curve %v", *keySize, *ecCurve)
+	}
+
+	return ecdsa.GenerateKey(curve, rand.Reader)
+}
+
+// readPrivateKey reads a PEM encoded PKCS #8, SEC 1 or PKCS #1 private key.
+func readPrivateKey(name string) crypto.Signer {
+	bytes, err := os.ReadFile(name)
+	if err != nil {
+		fatalln(err)
+	}
+
+	for len(bytes) > 0 {
+		var block *pem.Block
+		block, bytes = pem.Decode(bytes)
+		if block == nil {
+			break
+		}
+
+		var key any
+		switch block.Type {
+		case "PRIVATE KEY":
+			key, err = x509.ParsePKCS8PrivateKey(block.Bytes)
+		case "EC PRIVATE KEY":
+			key, err = x509.ParseECPrivateKey(block.Bytes)
+		case "RSA PRIVATE KEY":
+			key, err = x509.ParsePKCS1PrivateKey(block.Bytes)
+		default:
+			continue
+		}
+		if err != nil {
+			fatalln(err)
+		}
+
+		signer, ok := key.(crypto.Signer)
+		if !ok {
+			fatalf("unsupported private key type %T in %s\n", key, name)
+		}
+		return signer
+	}
+
+	fatalf("no private key found in %s\n", name)
+	return nil
+}
+
+func evaluatePolicy(names []string, format Format, jsonEncoded bool, policyFile string) {
+	f, err := os.Open(policyFile)
+	if err != nil {
+		fatalln(err)
+	}
+	defer f.Close()
+
+	policy, err := attestation.ParsePolicy(f)
+	if err != nil {
+		fatalln(err)
+	}
+
+	// JSON output is a single array, as for parse.
+	var entries []policyCertificate
+	passed := true
+	for _, name := range names {
+		results, err := evaluateCertificates(policy, name, readCertificates(name, format))
+		if err != nil {
+			fatalln(err)
+		}
+
+		for _, result := range results {
+			passed = passed && result.Result.Passed
+
+			if !jsonEncoded {
 				printer := &printer{
-					w:      output,
+					w:      os.Stdout,
 					prefix: "",
 					indent: "  ",
 				}
 
-				printer.Printf("%s / %d / %q\n", name, i, crt.Subject.String())
-				printKeyDescription(printer, keyDesc)
+				printer.Printf("%s / %d / %q\n", result.Name, result.Index, result.Subject)
+				printPolicyResult(printer, result.Result)
 			}
 		}
+		entries = append(entries, results...)
+	}
+
+	if jsonEncoded {
+		raw, err := json.MarshalIndent(entries, "", "  ")
+		if err != nil {
+			fatalln(err)
+		}
+		fmt.Println(string(raw))
+	}
+
+	if !passed {
+		os.Exit(1)
+	}
+}
+
+// evaluateCertificates evaluates policy against the certificates of the file name carrying the
+// key attestation extension. It fails if none does, as a policy is never satisfied without a
+// KeyDescription to evaluate.
+func evaluateCertificates(policy *attestation.Policy, name string, crts []*x509.Certificate) ([]policyCertificate, error) {
+	var results []policyCertificate
+	for i, crt := range crts {
+		ext := attestation.GetKeyExtension(crt)
+		if ext == nil {
+			continue
+		}
+
+		keyDesc, err := attestation.ParseExtension(ext.Value)
+		if err != nil {
+			return nil, err
+		}
+
+		results = append(results, policyCertificate{
+			Name:    name,
+			Index:   i,
+			Subject: crt.Subject.String(),
+			Result:  policy.Evaluate(keyDesc),
+		})
+	}
+
+	if len(results) == 0 {
+		return nil, fmt.Errorf("failed to get key extension (OID: %s) in %s", attestation.OIDKeyAttestationExtension.String(), name)
+	}
+	return results, nil
+}
+
+// policyCertificate is the JSON output of policy for a certificate.
+type policyCertificate struct {
+	Name    string
+	Index   int
+	Subject string
+	Result  attestation.PolicyResult
+}
+
+func printPolicyResult(printer *printer, result attestation.PolicyResult) {
+	if result.Passed {
+		printer.Printf("Policy: PASS\n")
+	} else {
+		printer.Printf("Policy: FAIL\n")
+	}
+	printRuleResults(printer, result.Results)
+}
+
+func printRuleResults(printer *printer, results []attestation.RuleResult) {
+	printer.Outdent()
+	defer printer.Indent()
+
+	for _, res := range results {
+		status := "PASS"
+		if !res.Passed {
+			status = "FAIL"
+		}
+		printer.Printf("[%s] %s: %s\n", status, res.Rule, res.Reason)
+		printRuleResults(printer, res.Children)
 	}
 }
 
@@ -258,35 +1126,53 @@ func printAuthorizationList(printer *printer, in attestation.AuthorizationList)
 	if v, ok := isNotEmpty(in.KeySize); ok {
 		printer.Printf("KeySize: %v\n", v)
 	}
+	if _, ok := isNotEmpty(in.BlockMode); ok {
+		printer.Printf("BlockMode: %v\n", in.BlockMode)
+	}
 	if _, ok := isNotEmpty(in.Digest); ok {
 		printer.Printf("Digest: %v\n", in.Digest)
 	}
 	if _, ok := isNotEmpty(in.Padding); ok {
 		printer.Printf("Padding: %v\n", in.Padding)
 	}
+	if _, ok := isNotEmpty(in.CallerNonce); ok {
+		printer.Printf("CallerNonce: %t\n", in.CallerNonce)
+	}
+	if v, ok := isNotEmpty(in.MinMacLength); ok {
+		printer.Printf("MinMacLength: %v\n", v)
+	}
 	if v, ok := isNotEmpty(in.EcCurve); ok {
 		printer.Printf("EcCurve: %v (%d)\n", v, v)
 	}
 	if v, ok := isNotEmpty(in.RsaPublicExponent); ok {
 		printer.Printf("RsaPublicExponent: %v\n", v)
 	}
+	if _, ok := isNotEmpty(in.MgfDigest); ok {
+		printer.Printf("MgfDigest: %v\n", in.MgfDigest)
+	}
 	if _, ok := isNotEmpty(in.RollbackResistance); ok {
 		printer.Printf("RollbackResistance: %t\n", in.RollbackResistance)
 	}
-	if _, ok := isNotEmpty(in.ActiveDateTime); ok {
-		printer.Printf("ActiveDateTime: %v\n", in.ActiveDateTime)
+	if _, ok := isNotEmpty(in.EarlyBootOnly); ok {
+		printer.Printf("EarlyBootOnly: %t\n", in.EarlyBootOnly)
 	}
-	if _, ok := isNotEmpty(in.OriginationExpireDateTime); ok {
-		printer.Printf("OriginationExpireDateTime: %v\n", in.OriginationExpireDateTime)
+	if v, ok := isNotEmpty(in.ActiveDateTime); ok {
+		printer.Printf("ActiveDateTime: %v\n", v)
 	}
-	if _, ok := isNotEmpty(in.UsageExpireDateTime); ok {
-		printer.Printf("UsageExpireDateTime: %v\n", in.UsageExpireDateTime)
+	if v, ok := isNotEmpty(in.OriginationExpireDateTime); ok {
+		printer.Printf("OriginationExpireDateTime: %v\n", v)
+	}
+	if v, ok := isNotEmpty(in.UsageExpireDateTime); ok {
+		printer.Printf("UsageExpireDateTime: %v\n", v)
+	}
+	if v, ok := isNotEmpty(in.UsageCountLimit); ok {
+		printer.Printf("UsageCountLimit: %v\n", v)
 	}
 	if _, ok := isNotEmpty(in.NoAuthRequired); ok {
 		printer.Printf("NoAuthRequired: %t\n", in.NoAuthRequired)
 	}
-	if _, ok := isNotEmpty(in.UserAuthType); ok {
-		printer.Printf("UserAuthType: %v\n", in.UserAuthType)
+	if v, ok := isNotEmpty(in.UserAuthType); ok {
+		printer.Printf("UserAuthType: %v (%d)\n", v, v)
 	}
 	if v, ok := isNotEmpty(in.AuthTimeout); ok {
 		printer.Printf("AuthTimeout: %v\n", v)
@@ -325,10 +1211,10 @@ func printAuthorizationList(printer *printer, in attestation.AuthorizationList)
 	}
 
 	if v, ok := isNotEmpty(in.OsVersion); ok {
-		printer.Printf("OsVersion: %v\n", v)
+		printer.Printf("OsVersion: %v (%d)\n", v, v)
 	}
 	if v, ok := isNotEmpty(in.OsPatchLevel); ok {
-		printer.Printf("OsPatchLevel: %v\n", v)
+		printer.Printf("OsPatchLevel: %v (%d)\n", v, v)
 	}
 
 	if in.AttestationApplicationId != nil {
@@ -361,10 +1247,25 @@ func printAuthorizationList(printer *printer, in attestation.AuthorizationList)
 		printer.Printf("AttestationIdModel: %s\n", in.AttestationIdModel)
 	}
 	if v, ok := isNotEmpty(in.VendorPatchLevel); ok {
-		printer.Printf("VendorPatchLevel: %v\n", v)
+		printer.Printf("VendorPatchLevel: %v (%d)\n", v, v)
 	}
 	if v, ok := isNotEmpty(in.BootPatchLevel); ok {
-		printer.Printf("BootPatchLevel: %v\n", v)
+		printer.Printf("BootPatchLevel: %v (%d)\n", v, v)
+	}
+	if _, ok := isNotEmpty(in.DeviceUniqueAttestation); ok {
+		printer.Printf("DeviceUniqueAttestation: %t\n", in.DeviceUniqueAttestation)
+	}
+	if _, ok := isNotEmpty(in.IdentityCredentialKey); ok {
+		printer.Printf("IdentityCredentialKey: %t\n", in.IdentityCredentialKey)
+	}
+	if _, ok := isNotEmpty(in.AttestationIdSecondImei); ok {
+		printer.Printf("AttestationIdSecondImei: %s\n", in.AttestationIdSecondImei)
+	}
+	if _, ok := isNotEmpty(in.ModuleHash); ok {
+		printer.Printf("ModuleHash: %x\n", in.ModuleHash)
+	}
+	for _, t := range in.Unknown {
+		printer.Printf("Unknown [%d]: %x\n", t.Tag, t.Value)
 	}
 }
 
@@ -389,6 +1290,39 @@ func printAttestationApplicationId(printer *printer, appId *attestation.Attestat
 	printer.Printf("SignatureDigests: %x\n", appId.SignatureDigests)
 }
 
+func printProvisioningInfo(printer *printer, info *attestation.ProvisioningInfo) {
+	printer.Outdent()
+	defer printer.Indent()
+
+	if v, ok := isNotEmpty(info.CertsIssued); ok {
+		printer.Printf("CertsIssued: %v\n", v)
+	}
+	if info.Manufacturer != "" {
+		printer.Printf("Manufacturer: %s\n", info.Manufacturer)
+	}
+}
+
+// readCertificates reads the X.509 certificates contained in a file.
+func readCertificates(name string, format Format) []*x509.Certificate {
+	bytes, err := os.ReadFile(name)
+	if err != nil {
+		fatalln(err)
+	}
+
+	var crts []*x509.Certificate
+	switch format.Get() {
+	case "PEM":
+		crts = parseCertsFromPEM(bytes)
+	case "DER":
+		crts, err = x509.ParseCertificates(bytes)
+		if err != nil {
+			fatalln(err)
+		}
+	}
+
+	return crts
+}
+
 // parseCertsFromPEM attempts to parse a series of PEM encoded certificates.
 // It appends any certificates found to s and reports whether any certificates were successfully parsed.
 //
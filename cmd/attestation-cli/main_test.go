@@ -0,0 +1,312 @@
+package main
+
+import (
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/mbreban/attestation"
+	"github.com/mbreban/attestation/attestationtest"
+)
+
+func newTestChain(t *testing.T, opts attestationtest.Options) *attestationtest.Chain {
+	t.Helper()
+
+	chain, err := attestationtest.NewChain(nil, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return chain
+}
+
+func Test_orderChain(t *testing.T) {
+	chain := newTestChain(t, attestationtest.Options{Intermediates: 2}).Certificates
+	leaf, intermediate1, intermediate2, root := chain[0], chain[1], chain[2], chain[3]
+	other := newTestChain(t, attestationtest.Options{}).Leaf()
+
+	tests := []struct {
+		name string
+		crts []*x509.Certificate
+		want []*x509.Certificate
+	}{
+		{
+			name: "shouldKeepOrderedChain",
+			crts: chain,
+			want: chain,
+		},
+		{
+			name: "shouldOrderShuffledChain",
+			crts: []*x509.Certificate{intermediate2, root, leaf, intermediate1},
+			want: chain,
+		},
+		{
+			name: "shouldOrderPartialChain",
+			crts: []*x509.Certificate{intermediate1, leaf},
+			want: []*x509.Certificate{leaf, intermediate1},
+		},
+		{
+			name: "shouldKeepOrderWithTwoLeaves",
+			crts: []*x509.Certificate{root, other, leaf},
+			want: []*x509.Certificate{root, other, leaf},
+		},
+		{
+			name: "shouldKeepOrderWithGap",
+			crts: []*x509.Certificate{root, leaf, intermediate2},
+			want: []*x509.Certificate{root, leaf, intermediate2},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := orderChain(tt.crts); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("orderChain() = %v, want %v", subjects(got), subjects(tt.want))
+			}
+		})
+	}
+}
+
+func Test_chainRole(t *testing.T) {
+	chain := newTestChain(t, attestationtest.Options{}).Certificates
+
+	want := []string{"leaf", "intermediate", "root"}
+	for i := range chain {
+		if got := chainRole(chain, i); got != want[i] {
+			t.Errorf("chainRole(%d) = %q, want %q", i, got, want[i])
+		}
+	}
+	if got := chainRole(chain[:2], 1); got != "intermediate" {
+		t.Errorf("chainRole() = %q for a chain without root, want %q", got, "intermediate")
+	}
+}
+
+func subjects(crts []*x509.Certificate) []string {
+	var out []string
+	for _, crt := range crts {
+		out = append(out, crt.Subject.String())
+	}
+	return out
+}
+
+func Test_runVerify(t *testing.T) {
+	chain := newTestChain(t, attestationtest.Options{})
+	missingExtension := newTestChain(t, attestationtest.Options{MissingExtension: true})
+
+	revocations, err := attestation.ParseRevocationList(strings.NewReader(
+		fmt.Sprintf(`{"entries": {%q: {"status": "REVOKED", "reason": "KEY_COMPROMISE"}}}`, chain.Leaf().SerialNumber.Text(16))))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	passing := mustParsePolicy(t, `{"rules": [{"type": "securityLevel", "levels": ["TrustedEnvironment"]}]}`)
+	failing := mustParsePolicy(t, `{"rules": [{"type": "securityLevel", "levels": ["StrongBox"]}]}`)
+
+	tests := []struct {
+		name          string
+		crts          []*x509.Certificate
+		opts          attestation.VerifyOptions
+		wantChallenge []byte
+		policy        *attestation.Policy
+		wantCode      int
+		wantStatus    []string
+	}{
+		{
+			name:          "shouldPass",
+			crts:          chain.Certificates,
+			opts:          attestation.VerifyOptions{Roots: []*x509.Certificate{chain.Root()}},
+			wantChallenge: chain.KeyDescription.AttestationChallenge,
+			policy:        passing,
+			wantCode:      exitOK,
+			wantStatus:    []string{"PASS", "PASS", "PASS"},
+		},
+		{
+			name:       "shouldSkipUnrequestedChecks",
+			crts:       chain.Certificates,
+			opts:       attestation.VerifyOptions{Roots: []*x509.Certificate{chain.Root()}},
+			wantCode:   exitOK,
+			wantStatus: []string{"PASS", "SKIP", "SKIP"},
+		},
+		{
+			name:       "shouldFailWithEmptyChain",
+			wantCode:   exitInvalidChain,
+			wantStatus: []string{"FAIL", "SKIP", "SKIP"},
+		},
+		{
+			name:       "shouldFailWithUntrustedRoot",
+			crts:       chain.Certificates,
+			wantCode:   exitInvalidChain,
+			wantStatus: []string{"FAIL", "SKIP", "SKIP"},
+		},
+		{
+			name:       "shouldFailWhenRevoked",
+			crts:       chain.Certificates,
+			opts:       attestation.VerifyOptions{Roots: []*x509.Certificate{chain.Root()}, Revocations: revocations},
+			wantCode:   exitRevoked,
+			wantStatus: []string{"FAIL", "SKIP", "SKIP"},
+		},
+		{
+			name:       "shouldFailWithMissingExtension",
+			crts:       missingExtension.Certificates,
+			opts:       attestation.VerifyOptions{Roots: []*x509.Certificate{missingExtension.Root()}},
+			policy:     passing,
+			wantCode:   exitInvalidExtension,
+			wantStatus: []string{"FAIL", "SKIP", "SKIP"},
+		},
+		{
+			name:          "shouldFailWithChallengeMismatch",
+			crts:          chain.Certificates,
+			opts:          attestation.VerifyOptions{Roots: []*x509.Certificate{chain.Root()}},
+			wantChallenge: []byte("other"),
+			policy:        passing,
+			wantCode:      exitChallengeMismatch,
+			wantStatus:    []string{"PASS", "FAIL", "SKIP"},
+		},
+		{
+			name:       "shouldFailWithPolicy",
+			crts:       chain.Certificates,
+			opts:       attestation.VerifyOptions{Roots: []*x509.Certificate{chain.Root()}},
+			policy:     failing,
+			wantCode:   exitPolicyFailed,
+			wantStatus: []string{"PASS", "SKIP", "FAIL"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			report, code := runVerify(tt.crts, tt.opts, tt.wantChallenge, tt.policy)
+			if code != tt.wantCode {
+				t.Errorf("runVerify() code = %d, want %d (checks %+v)", code, tt.wantCode, report.Checks)
+			}
+			if report.Passed != (tt.wantCode == exitOK) {
+				t.Errorf("runVerify() Passed = %v, want %v", report.Passed, tt.wantCode == exitOK)
+			}
+
+			var status []string
+			for _, c := range report.Checks {
+				status = append(status, c.Status)
+			}
+			if !reflect.DeepEqual(status, tt.wantStatus) {
+				t.Errorf("runVerify() checks = %+v, want status %v", report.Checks, tt.wantStatus)
+			}
+		})
+	}
+}
+
+func Test_evaluateCertificates(t *testing.T) {
+	chain := newTestChain(t, attestationtest.Options{})
+	policy := mustParsePolicy(t, `{"rules": [{"type": "securityLevel", "levels": ["TrustedEnvironment"]}]}`)
+
+	results, err := evaluateCertificates(policy, "chain.pem", chain.Certificates)
+	if err != nil {
+		t.Fatalf("evaluateCertificates() error = %v", err)
+	}
+	if len(results) != 1 || results[0].Index != 0 || !results[0].Result.Passed {
+		t.Errorf("evaluateCertificates() = %+v, want a passing result for the leaf", results)
+	}
+
+	if _, err := evaluateCertificates(policy, "plain.pem", chain.Certificates[1:]); err == nil {
+		t.Error("evaluateCertificates() error = nil without key extension, want error")
+	}
+}
+
+func mustParsePolicy(t *testing.T, s string) *attestation.Policy {
+	t.Helper()
+
+	policy, err := attestation.ParsePolicy(strings.NewReader(s))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return policy
+}
+
+func Test_parseTemplate(t *testing.T) {
+	keyDesc := &attestation.KeyDescription{
+		AttestationVersion:   attestation.KAKeyMintVersion3,
+		KeymasterVersion:     attestation.KeyMintVersion3,
+		AttestationChallenge: []byte("challenge"),
+		UniqueId:             []byte{},
+	}
+	bare, err := json.Marshal(keyDesc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	parsed, err := json.Marshal([]parsedCertificate{
+		{Name: "chain.pem", Index: 0, ProvisioningInfo: &attestation.ProvisioningInfo{}},
+		{Name: "chain.pem", Index: 1, KeyDescription: keyDesc},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name    string
+		data    string
+		wantErr bool
+	}{
+		{name: "shouldSucceedWithKeyDescription", data: string(bare)},
+		{name: "shouldSucceedWithWrapper", data: `{"KeyDescription": ` + string(bare) + `}`},
+		{name: "shouldSucceedWithParseOutput", data: "\n" + string(parsed)},
+		{name: "shouldFailWithoutKeyDescription", data: `[{"Name": "chain.pem", "Index": 0}]`, wantErr: true},
+		{name: "shouldFailWithInvalidJSON", data: `{`, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := parseTemplate([]byte(tt.data))
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("parseTemplate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err == nil && !reflect.DeepEqual(got, keyDesc) {
+				t.Errorf("parseTemplate() = %+v, want %+v", got, keyDesc)
+			}
+		})
+	}
+}
+
+func Test_generateKey(t *testing.T) {
+	algorithm := func(v attestation.Algorithm) *attestation.Algorithm { return &v }
+	keySize := func(v int) *int { return &v }
+	ecCurve := func(v attestation.EcCurve) *attestation.EcCurve { return &v }
+
+	tests := []struct {
+		name     string
+		authList attestation.AuthorizationList
+		want     string
+		wantErr  bool
+	}{
+		{name: "shouldDefaultToP256", want: "P-256"},
+		{name: "shouldUseEcCurve", authList: attestation.AuthorizationList{EcCurve: ecCurve(attestation.CurveP521)}, want: "P-521"},
+		{name: "shouldUseKeySize", authList: attestation.AuthorizationList{Algorithm: algorithm(attestation.AlgoEC), KeySize: keySize(384)}, want: "P-384"},
+		{name: "shouldUseCurve25519", authList: attestation.AuthorizationList{EcCurve: ecCurve(attestation.Curve25519)}, want: "Ed25519"},
+		{name: "shouldUseRSA", authList: attestation.AuthorizationList{Algorithm: algorithm(attestation.AlgoRSA), KeySize: keySize(1024)}, want: "RSA-1024"},
+		{name: "shouldFailWithMismatchingKeySize", authList: attestation.AuthorizationList{KeySize: keySize(256), EcCurve: ecCurve(attestation.CurveP384)}, wantErr: true},
+		{name: "shouldFailWithUnsupportedKeySize", authList: attestation.AuthorizationList{KeySize: keySize(192)}, wantErr: true},
+		{name: "shouldFailWithSymmetricAlgorithm", authList: attestation.AuthorizationList{Algorithm: algorithm(attestation.AlgoAES)}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			key, err := generateKey(&attestation.KeyDescription{TeeEnforced: tt.authList})
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("generateKey() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err != nil {
+				return
+			}
+
+			var got string
+			switch k := key.(type) {
+			case *ecdsa.PrivateKey:
+				got = k.Curve.Params().Name
+			case ed25519.PrivateKey:
+				got = "Ed25519"
+			case *rsa.PrivateKey:
+				got = fmt.Sprintf("RSA-%d", k.N.BitLen())
+			}
+			if got != tt.want {
+				t.Errorf("generateKey() = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,65 @@
+package attestation
+
+import (
+	"fmt"
+	"strconv"
+	"time"
+)
+
+// dateTimeLayout is RFC 3339 with up to millisecond precision.
+const dateTimeLayout = "2006-01-02T15:04:05.999Z07:00"
+
+// DateTime is a date and time with millisecond precision, as used by the ActiveDateTime,
+// OriginationExpireDateTime, UsageExpireDateTime and CreationDateTime authorizations.
+//
+// Its value is the number of milliseconds elapsed since the Unix epoch.
+type DateTime int64
+
+// NewDateTime returns the DateTime corresponding to t, truncated to the millisecond.
+func NewDateTime(t time.Time) DateTime {
+	return DateTime(t.UnixMilli())
+}
+
+// Time returns the DateTime as a UTC time.Time.
+func (d DateTime) Time() time.Time {
+	return time.UnixMilli(int64(d)).UTC()
+}
+
+// String returns the RFC 3339 representation. RFC 3339 only covers years 0 to 9999, so other
+// dates are represented by their decimal number of milliseconds.
+func (d DateTime) String() string {
+	t := d.Time()
+	if t.Year() < 0 || t.Year() > 9999 {
+		return strconv.FormatInt(int64(d), 10)
+	}
+	return t.Format(dateTimeLayout)
+}
+
+// MarshalText implements the encoding.TextMarshaler interface.
+func (d DateTime) MarshalText() ([]byte, error) {
+	return []byte(d.String()), nil
+}
+
+// UnmarshalText implements the encoding.TextUnmarshaler interface.
+func (d *DateTime) UnmarshalText(text []byte) error {
+	v, err := ParseDateTime(string(text))
+	if err != nil {
+		return err
+	}
+	*d = v
+	return nil
+}
+
+// ParseDateTime parses an RFC 3339 date and time, truncated to the millisecond, or a decimal
+// number of milliseconds as returned by String for dates outside of RFC 3339.
+func ParseDateTime(s string) (DateTime, error) {
+	if v, err := strconv.ParseInt(s, 10, 64); err == nil {
+		return DateTime(v), nil
+	}
+
+	t, err := time.Parse(time.RFC3339, s)
+	if err != nil {
+		return 0, fmt.Errorf("attestation: invalid DateTime %q", s)
+	}
+	return NewDateTime(t), nil
+}
@@ -0,0 +1,107 @@
+package attestation
+
+import (
+	"encoding/json"
+	"math"
+	"testing"
+	"time"
+)
+
+func TestDateTime(t *testing.T) {
+	tests := []struct {
+		name string
+		d    DateTime
+		want string
+	}{
+		{name: "shouldSucceedWithEpoch", d: 0, want: "1970-01-01T00:00:00Z"},
+		{name: "shouldSucceedWithMilliseconds", d: 1652827723244, want: "2022-05-17T22:48:43.244Z"},
+		{name: "shouldSucceedBeforeEpoch", d: -1500, want: "1969-12-31T23:59:58.5Z"},
+		{name: "shouldSucceedWithLastRFC3339Year", d: 253402300799999, want: "9999-12-31T23:59:59.999Z"},
+		{name: "shouldSucceedAfterRFC3339", d: 253402300800000, want: "253402300800000"},
+		{name: "shouldSucceedBeforeRFC3339", d: -62167219200001, want: "-62167219200001"},
+		{name: "shouldSucceedWithMax", d: math.MaxInt64, want: "9223372036854775807"},
+		{name: "shouldSucceedWithMin", d: math.MinInt64, want: "-9223372036854775808"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.d.String(); got != tt.want {
+				t.Errorf("String() = %q, want %q", got, tt.want)
+			}
+			if got, err := ParseDateTime(tt.want); err != nil || got != tt.d {
+				t.Errorf("ParseDateTime() = %d, %v, want %d", got, err, tt.d)
+			}
+			if got := NewDateTime(tt.d.Time()); got != tt.d {
+				t.Errorf("NewDateTime() = %d, want %d", got, tt.d)
+			}
+		})
+	}
+}
+
+func TestParseDateTime(t *testing.T) {
+	tests := []struct {
+		name    string
+		s       string
+		want    DateTime
+		wantErr bool
+	}{
+		{name: "shouldFailWhenEmpty", s: "", wantErr: true},
+		{name: "shouldFailWithoutTimeZone", s: "2022-05-17T22:48:43", wantErr: true},
+		{name: "shouldSucceedWithTimeZone", s: "2022-05-18T00:48:43.244+02:00", want: 1652827723244},
+		{name: "shouldTruncateToMilliseconds", s: "2022-05-17T22:48:43.244999Z", want: 1652827723244},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseDateTime(tt.s)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("ParseDateTime() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if got != tt.want {
+				t.Errorf("ParseDateTime() = %d, want %d", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDateTime_JSON(t *testing.T) {
+	d := NewDateTime(time.Date(2024, time.February, 29, 12, 0, 0, 0, time.UTC))
+	in := AuthorizationList{CreationDateTime: &d}
+
+	raw, err := json.Marshal(in)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var out struct{ CreationDateTime string }
+	if err := json.Unmarshal(raw, &out); err != nil {
+		t.Fatal(err)
+	}
+	if out.CreationDateTime != "2024-02-29T12:00:00Z" {
+		t.Errorf("Marshal() CreationDateTime = %q, want %q", out.CreationDateTime, "2024-02-29T12:00:00Z")
+	}
+
+	var got AuthorizationList
+	if err := json.Unmarshal(raw, &got); err != nil {
+		t.Fatal(err)
+	}
+	if got.CreationDateTime == nil || *got.CreationDateTime != d {
+		t.Errorf("Unmarshal() CreationDateTime = %v, want %v", got.CreationDateTime, d)
+	}
+}
+
+func TestDateTime_JSONOutOfRFC3339(t *testing.T) {
+	for _, d := range []DateTime{math.MinInt64, math.MaxInt64} {
+		raw, err := json.Marshal(AuthorizationList{ActiveDateTime: &d})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		var got AuthorizationList
+		if err := json.Unmarshal(raw, &got); err != nil {
+			t.Fatalf("Unmarshal(%s) error = %v", raw, err)
+		}
+		if got.ActiveDateTime == nil || *got.ActiveDateTime != d {
+			t.Errorf("Unmarshal() ActiveDateTime = %v, want %v", got.ActiveDateTime, d)
+		}
+	}
+}
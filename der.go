@@ -0,0 +1,168 @@
+package attestation
+
+import (
+	"bytes"
+	"encoding/asn1"
+	"slices"
+
+	"golang.org/x/crypto/cryptobyte"
+	cryptobyte_asn1 "golang.org/x/crypto/cryptobyte/asn1"
+)
+
+// element is a single DER encoded TLV.
+//
+// Unlike cryptobyte.String.ReadASN1, it supports the high-tag-number form used by the
+// authorization list tags.
+type element struct {
+	class       int
+	tag         int
+	constructed bool
+	full        []byte // identifier, length and content octets
+	content     []byte
+}
+
+// readElement reads a DER encoded TLV from s and advances. It reports whether the read was
+// successful.
+func readElement(s *cryptobyte.String, e *element) bool {
+	input := *s
+
+	var b uint8
+	if !input.ReadUint8(&b) {
+		return false
+	}
+	class := int(b >> 6)
+	constructed := b&0x20 != 0
+	tag := int(b & 0x1f)
+
+	// ITU-T X.690 section 8.1.2.4: high-tag-number form.
+	if tag == 0x1f {
+		tag = 0
+		for i := 0; ; i++ {
+			if !input.ReadUint8(&b) {
+				return false
+			}
+			// The first subsequent octet shall not be 0x80 and the tag must fit an int.
+			if (i == 0 && b == 0x80) || i >= 4 {
+				return false
+			}
+			tag = tag<<7 | int(b&0x7f)
+			if b&0x80 == 0 {
+				break
+			}
+		}
+		// Tags lower than 31 must use the low-tag-number form.
+		if tag < 0x1f {
+			return false
+		}
+	}
+
+	if !input.ReadUint8(&b) {
+		return false
+	}
+	length := int(b)
+	if b&0x80 != 0 {
+		n := int(b & 0x7f)
+		if n == 0 || n > 4 {
+			return false
+		}
+		length = 0
+		for i := 0; i < n; i++ {
+			if !input.ReadUint8(&b) {
+				return false
+			}
+			// DER requires the minimum number of length octets.
+			if i == 0 && b == 0 {
+				return false
+			}
+			length = length<<8 | int(b)
+		}
+		if length < 0x80 {
+			return false
+		}
+	}
+
+	headerLen := len(*s) - len(input)
+	if length < 0 || len(input) < length {
+		return false
+	}
+
+	*e = element{
+		class:       class,
+		tag:         tag,
+		constructed: constructed,
+		full:        (*s)[:headerLen+length],
+		content:     (*s)[headerLen : headerLen+length],
+	}
+	*s = (*s)[headerLen+length:]
+
+	return true
+}
+
+// appendHeader appends the DER identifier and length octets of a TLV to dst.
+func appendHeader(dst []byte, class, tag int, constructed bool, length int) []byte {
+	b := uint8(class) << 6
+	if constructed {
+		b |= 0x20
+	}
+
+	if tag < 0x1f {
+		dst = append(dst, b|uint8(tag))
+	} else {
+		dst = append(dst, b|0x1f)
+		n := 1
+		for t := tag >> 7; t > 0; t >>= 7 {
+			n++
+		}
+		for i := n - 1; i >= 0; i-- {
+			o := uint8(tag>>(7*i)) & 0x7f
+			if i > 0 {
+				o |= 0x80
+			}
+			dst = append(dst, o)
+		}
+	}
+
+	if length < 0x80 {
+		return append(dst, uint8(length))
+	}
+
+	n := 1
+	for l := length >> 8; l > 0; l >>= 8 {
+		n++
+	}
+	dst = append(dst, 0x80|uint8(n))
+	for i := n - 1; i >= 0; i-- {
+		dst = append(dst, uint8(length>>(8*i)))
+	}
+
+	return dst
+}
+
+// addExplicit adds to b a constructed context-specific TLV with the given tag, whose content is
+// built by f.
+//
+// Unlike cryptobyte.Builder.AddASN1, it supports the high-tag-number form.
+func addExplicit(b *cryptobyte.Builder, tag int, f cryptobyte.BuilderContinuation) {
+	child := cryptobyte.NewBuilder(nil)
+	f(child)
+	content, err := child.Bytes()
+	if err != nil {
+		b.SetError(err)
+		return
+	}
+
+	b.AddBytes(appendHeader(nil, asn1.ClassContextSpecific, tag, true, len(content)))
+	b.AddBytes(content)
+}
+
+// addSetOf adds to b a SET OF the encoded elements, sorted as required by DER.
+func addSetOf(b *cryptobyte.Builder, elements [][]byte) {
+	elements = slices.Clone(elements)
+	slices.SortFunc(elements, bytes.Compare)
+
+	b.AddASN1(cryptobyte_asn1.SET, func(b *cryptobyte.Builder) {
+		for _, e := range elements {
+			b.AddBytes(e)
+		}
+	})
+}
@@ -0,0 +1,109 @@
+package attestation
+
+import (
+	"reflect"
+	"testing"
+
+	"golang.org/x/crypto/cryptobyte"
+)
+
+func Test_readElement(t *testing.T) {
+	tests := []struct {
+		name  string
+		input []byte
+		want  element
+		ok    bool
+	}{
+		{
+			name:  "shouldFailWhenEmpty",
+			input: []byte{},
+		},
+		{
+			name:  "shouldFailWhenTruncated",
+			input: []byte{0x04, 0x02, 0x00},
+		},
+		{
+			name:  "shouldFailWithNonMinimalLength",
+			input: []byte{0x04, 0x81, 0x01, 0x00},
+		},
+		{
+			name:  "shouldFailWithNonMinimalTag",
+			input: []byte{0xbf, 0x0a, 0x00},
+		},
+		{
+			name:  "shouldFailWithPaddedTag",
+			input: []byte{0xbf, 0x80, 0x85, 0x52, 0x00},
+		},
+		{
+			name:  "shouldSucceedWithLowTag",
+			input: []byte{0x04, 0x01, 0xaa},
+			want:  element{class: 0, tag: 4, full: []byte{0x04, 0x01, 0xaa}, content: []byte{0xaa}},
+			ok:    true,
+		},
+		{
+			name:  "shouldSucceedWithHighTag",
+			input: []byte{0xbf, 0x85, 0x52, 0x02, 0x05, 0x00},
+			want: element{
+				class:       2,
+				tag:         722,
+				constructed: true,
+				full:        []byte{0xbf, 0x85, 0x52, 0x02, 0x05, 0x00},
+				content:     []byte{0x05, 0x00},
+			},
+			ok: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got element
+			s := cryptobyte.String(tt.input)
+			if ok := readElement(&s, &got); ok != tt.ok {
+				t.Errorf("readElement() = %v, want %v", ok, tt.ok)
+				return
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("readElement() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_appendHeader(t *testing.T) {
+	tests := []struct {
+		name        string
+		class       int
+		tag         int
+		constructed bool
+		length      int
+		want        []byte
+	}{
+		{
+			name:   "shouldEncodeLowTag",
+			tag:    4,
+			length: 1,
+			want:   []byte{0x04, 0x01},
+		},
+		{
+			name:        "shouldEncodeHighTag",
+			class:       2,
+			tag:         722,
+			constructed: true,
+			length:      2,
+			want:        []byte{0xbf, 0x85, 0x52, 0x02},
+		},
+		{
+			name:        "shouldEncodeLongLength",
+			tag:         16,
+			constructed: true,
+			length:      0x123,
+			want:        []byte{0x30, 0x82, 0x01, 0x23},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := appendHeader(nil, tt.class, tt.tag, tt.constructed, tt.length); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("appendHeader() = %x, want %x", got, tt.want)
+			}
+		})
+	}
+}
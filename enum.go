@@ -0,0 +1,40 @@
+package attestation
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// enum is implemented by the enumerated types of this package.
+type enum interface {
+	~uint
+}
+
+// enumString returns the name of v, or typ(v) when v has no name.
+func enumString[T enum](typ string, names map[T]string, v T) string {
+	if name, ok := names[v]; ok {
+		return name
+	}
+	return typ + "(" + strconv.FormatUint(uint64(v), 10) + ")"
+}
+
+// parseEnum parses the name of a value of type typ, case-insensitively. It also accepts the
+// typ(N) form returned by enumString for values without a name.
+func parseEnum[T enum](typ string, names map[T]string, s string) (T, error) {
+	for v, name := range names {
+		if strings.EqualFold(s, name) {
+			return v, nil
+		}
+	}
+
+	if n, ok := strings.CutPrefix(s, typ+"("); ok {
+		if n, ok := strings.CutSuffix(n, ")"); ok {
+			if v, err := strconv.ParseUint(n, 10, 0); err == nil {
+				return T(v), nil
+			}
+		}
+	}
+
+	return 0, fmt.Errorf("attestation: invalid %s %q", typ, s)
+}
@@ -0,0 +1,188 @@
+package attestation
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"testing"
+)
+
+func TestEnum_String(t *testing.T) {
+	tests := []struct {
+		name string
+		v    fmt.Stringer
+		want string
+	}{
+		{name: "shouldSucceedWithSecurityLevel", v: StrongBox, want: "StrongBox"},
+		{name: "shouldSucceedWithUnknownSecurityLevel", v: SecurityLevel(42), want: "SecurityLevel(42)"},
+		{name: "shouldSucceedWithUnknownVerifiedBootState", v: VerifiedBootState(4), want: "VerifiedBootState(4)"},
+		{name: "shouldSucceedWithBlockModeGCM", v: BlockModeGCM, want: "GCM"},
+		{name: "shouldSucceedWithPaddingPKCS7", v: PaddingPKCS7, want: "PKCS7"},
+		{name: "shouldSucceedWithUnknownPadding", v: PaddingMode(0), want: "PaddingMode(0)"},
+		{name: "shouldSucceedWithHwAuthTypeAny", v: HwAuthTypeAny, want: "ANY"},
+		{name: "shouldSucceedWithUnknownPurpose", v: KeyPurpose(42), want: "KeyPurpose(42)"},
+		{name: "shouldSucceedWithUnknownDigest", v: Digest(42), want: "Digest(42)"},
+		{name: "shouldSucceedWithUnknownAttestationVersion", v: AttestationVersion(42), want: "AttestationVersion(42)"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.v.String(); got != tt.want {
+				t.Errorf("String() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestEnum_keyMintValues(t *testing.T) {
+	// Values from the KeyMint AIDL definitions.
+	tests := []struct {
+		name string
+		v    fmt.Stringer
+		want uint
+	}{
+		{name: "TRIPLE_DES", v: AlgoTripleDES, want: 33},
+		{name: "ECB", v: BlockModeECB, want: 1},
+		{name: "GCM", v: BlockModeGCM, want: 32},
+		{name: "CURVE_25519", v: Curve25519, want: 4},
+		{name: "SECURELY_IMPORTED", v: KeyOriginSecurelyImported, want: 4},
+		{name: "PKCS7", v: PaddingPKCS7, want: 64},
+		{name: "AGREE_KEY", v: PurposeAgreeKey, want: 6},
+		{name: "ATTEST_KEY", v: PurposeAttestKey, want: 7},
+		{name: "PASSWORD", v: HwAuthTypePassword, want: 1},
+		{name: "FINGERPRINT", v: HwAuthTypeFingerprint, want: 2},
+		{name: "ANY", v: HwAuthTypeAny, want: 0xffffffff},
+		{name: "Keystore", v: Keystore, want: 100},
+		{name: "KeyMint version 4.0", v: KAKeyMintVersion4, want: 400},
+		{name: "KeyMint version 4.0", v: KeyMintVersion4, want: 400},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := reflect.ValueOf(tt.v).Uint(); got != uint64(tt.want) {
+				t.Errorf("%T = %d, want %d", tt.v, got, tt.want)
+			}
+			if got := tt.v.String(); got != tt.name {
+				t.Errorf("String() = %q, want %q", got, tt.name)
+			}
+		})
+	}
+}
+
+func TestParseKeyPurpose(t *testing.T) {
+	tests := []struct {
+		name    string
+		s       string
+		want    KeyPurpose
+		wantErr bool
+	}{
+		{name: "shouldFailWhenEmpty", s: "", wantErr: true},
+		{name: "shouldFailWhenUnknown", s: "UNKNOWN", wantErr: true},
+		{name: "shouldFailWhenNotANumber", s: "KeyPurpose(x)", wantErr: true},
+		{name: "shouldSucceedWithName", s: "SIGN", want: PurposeSign},
+		{name: "shouldSucceedWithLowerCaseName", s: "wrap_key", want: PurposeWrapKey},
+		{name: "shouldSucceedWithNumber", s: "KeyPurpose(42)", want: KeyPurpose(42)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseKeyPurpose(tt.s)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("ParseKeyPurpose() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if got != tt.want {
+				t.Errorf("ParseKeyPurpose() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestEnum_JSON(t *testing.T) {
+	type enums struct {
+		Level   SecurityLevel
+		State   VerifiedBootState
+		Purpose []KeyPurpose
+		Padding *PaddingMode
+		Origin  KeyOrigin
+	}
+
+	padding := PaddingPKCS7
+	in := enums{
+		Level:   TrustedEnvironment,
+		State:   SelfSigned,
+		Purpose: []KeyPurpose{PurposeSign, KeyPurpose(42)},
+		Padding: &padding,
+		Origin:  KeyOriginImported,
+	}
+	want := `{"Level":"TrustedEnvironment","State":"SelfSigned","Purpose":["SIGN","KeyPurpose(42)"],"Padding":"PKCS7","Origin":"IMPORTED"}`
+
+	got, err := json.Marshal(in)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(got) != want {
+		t.Errorf("Marshal() = %s, want %s", got, want)
+	}
+
+	var out enums
+	if err := json.Unmarshal(got, &out); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(out, in) {
+		t.Errorf("Unmarshal() = %+v, want %+v", out, in)
+	}
+
+	if err := json.Unmarshal([]byte(`{"Level":"Hardware"}`), &out); err == nil {
+		t.Error("Unmarshal() error = nil, want error")
+	}
+}
+
+func TestHardwareAuthenticatorType(t *testing.T) {
+	tests := []struct {
+		name      string
+		v         HardwareAuthenticatorType
+		want      string
+		wantFlags []HardwareAuthenticatorType
+	}{
+		{name: "shouldSucceedWithNone", v: HwAuthTypeNone, want: "NONE"},
+		{name: "shouldSucceedWithPassword", v: HwAuthTypePassword, want: "PASSWORD", wantFlags: []HardwareAuthenticatorType{HwAuthTypePassword}},
+		{
+			name:      "shouldSucceedWithPasswordAndFingerprint",
+			v:         HwAuthTypePassword | HwAuthTypeFingerprint,
+			want:      "PASSWORD|FINGERPRINT",
+			wantFlags: []HardwareAuthenticatorType{HwAuthTypePassword, HwAuthTypeFingerprint},
+		},
+		{
+			name:      "shouldSucceedWithUnknownFlag",
+			v:         HwAuthTypeFingerprint | 0x8,
+			want:      "FINGERPRINT|0x8",
+			wantFlags: []HardwareAuthenticatorType{HwAuthTypeFingerprint, 0x8},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.v.String(); got != tt.want {
+				t.Errorf("String() = %q, want %q", got, tt.want)
+			}
+			if got := tt.v.Flags(); !reflect.DeepEqual(got, tt.wantFlags) {
+				t.Errorf("Flags() = %v, want %v", got, tt.wantFlags)
+			}
+			for _, flag := range tt.wantFlags {
+				if !tt.v.Has(flag) {
+					t.Errorf("Has(%v) = false, want true", flag)
+				}
+			}
+			if got, err := ParseHardwareAuthenticatorType(tt.want); err != nil || got != tt.v {
+				t.Errorf("ParseHardwareAuthenticatorType() = %v, %v, want %v", got, err, tt.v)
+			}
+		})
+	}
+
+	if any := HwAuthTypeAny; !any.Has(HwAuthTypePassword|HwAuthTypeFingerprint) || any.String() != "ANY" {
+		t.Errorf("HwAuthTypeAny = %v, want ANY with all flags", any)
+	}
+	if HwAuthTypePassword.Has(HwAuthTypeFingerprint) || HwAuthTypePassword.Has(HwAuthTypeNone) {
+		t.Error("HwAuthTypePassword.Has() = true, want false")
+	}
+	if _, err := ParseHardwareAuthenticatorType("PASSWORD|IRIS"); err == nil {
+		t.Error("ParseHardwareAuthenticatorType() error = nil, want error")
+	}
+}
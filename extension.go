@@ -2,513 +2,785 @@ package attestation
 
 import (
 	"bytes"
+	"cmp"
 	"crypto/x509"
 	"crypto/x509/pkix"
 	"encoding/asn1"
 	"errors"
 	"fmt"
+	"math"
+	"slices"
+	"strconv"
 
 	"golang.org/x/crypto/cryptobyte"
 	cryptobyte_asn1 "golang.org/x/crypto/cryptobyte/asn1"
 )
 
-var errNoData = errors.New("attestation: no data")
-
-func newInt(v asn1.RawValue, a any) error {
-	if len(v.Bytes) == 0 {
-		return errNoData
+// CreateKeyDescription creates a new KeyDescription based on a template.
+//
+// The KeyDescription is encoded in DER the way KeyMint encodes it: the tags of each
+// AuthorizationList, including template.SoftwareEnforced.Unknown and
+// template.TeeEnforced.Unknown, are in ascending order, SET OF elements are sorted by encoding,
+// and nil optional values, empty sets and false NULL values are omitted. An empty but non-nil
+// OCTET STRING is encoded, as ParseExtension keeps absent and empty values distinct. Equal
+// templates always produce the same encoding.
+func CreateKeyDescription(template *KeyDescription) ([]byte, error) {
+	if template == nil {
+		return nil, errors.New("attestation: template is nil")
 	}
 
-	if rest, err := asn1.Unmarshal(v.Bytes, a); err != nil {
-		return err
-	} else if len(rest) != 0 {
-		return errors.New("attestation: trailing data after Integer")
-	}
+	b := cryptobyte.NewBuilder(nil)
+	b.AddASN1(cryptobyte_asn1.SEQUENCE, func(b *cryptobyte.Builder) {
+		b.AddASN1Int64(int64(template.AttestationVersion))
+		b.AddASN1Enum(int64(template.AttestationSecurityLevel))
+		b.AddASN1Int64(int64(template.KeymasterVersion))
+		b.AddASN1Enum(int64(template.KeymasterSecurityLevel))
+		b.AddASN1OctetString(template.AttestationChallenge)
+		b.AddASN1OctetString(template.UniqueId)
+		addAuthorizationList(b, &template.SoftwareEnforced)
+		addAuthorizationList(b, &template.TeeEnforced)
+	})
 
-	return nil
+	return b.Bytes()
 }
 
-func newOptionnalInt(v asn1.RawValue) (*int, error) {
-	var i int
-	if err := newInt(v, &i); err != nil {
-		if err == errNoData {
-			return nil, nil
-		}
-		return nil, err
-	}
-	return &i, nil
+func marshalAuthorizationList(authList *AuthorizationList) ([]byte, error) {
+	b := cryptobyte.NewBuilder(nil)
+	addAuthorizationList(b, authList)
+	return b.Bytes()
 }
 
-func newOptionnalInt32(v asn1.RawValue) (*int32, error) {
-	var i int32
-	if err := newInt(v, &i); err != nil {
-		if err == errNoData {
-			return nil, nil
+// addAuthorizationList adds the DER encoding of authList to b.
+func addAuthorizationList(b *cryptobyte.Builder, authList *AuthorizationList) {
+	if authList == nil {
+		b.SetError(errors.New("attestation: AuthorizationList is nil"))
+		return
+	}
+
+	unknown := slices.Clone(authList.Unknown)
+	slices.SortStableFunc(unknown, func(a, b RawTag) int {
+		return cmp.Compare(a.Tag, b.Tag)
+	})
+	for i, t := range unknown {
+		if _, ok := authorizationListTags[t.Tag]; ok {
+			b.SetError(fmt.Errorf("attestation: unknown tag %d is a known tag", t.Tag))
+			return
 		}
-		return nil, err
-	}
-	return &i, nil
-}
-
-func newOptionnalInt64(v asn1.RawValue) (*int64, error) {
-	var i int64
-	if err := newInt(v, &i); err != nil {
-		if err == errNoData {
-			return nil, nil
+		if i > 0 && unknown[i-1].Tag == t.Tag {
+			b.SetError(fmt.Errorf("attestation: duplicate unknown tag %d", t.Tag))
+			return
 		}
-		return nil, err
 	}
-	return &i, nil
-}
 
-func newIntRawValue(v *int, tag int) (asn1.RawValue, error) {
-	if v == nil {
-		return asn1.RawValue{}, nil
-	}
-	return newAnyRawValue(*v, tag)
+	b.AddASN1(cryptobyte_asn1.SEQUENCE, func(b *cryptobyte.Builder) {
+		a := &authorizationListBuilder{b: b, unknown: unknown}
+		addIntegerSet(a, TagPurpose, authList.Purpose)
+		addInteger(a, TagAlgorithm, authList.Algorithm)
+		addInteger(a, TagKeySize, authList.KeySize)
+		addIntegerSet(a, TagBlockMode, authList.BlockMode)
+		addIntegerSet(a, TagDigest, authList.Digest)
+		addIntegerSet(a, TagPadding, authList.Padding)
+		a.addNull(TagCallerNonce, authList.CallerNonce)
+		addInteger(a, TagMinMacLength, authList.MinMacLength)
+		addInteger(a, TagEcCurve, authList.EcCurve)
+		addInteger(a, TagRsaPublicExponent, authList.RsaPublicExponent)
+		addIntegerSet(a, TagMgfDigest, authList.MgfDigest)
+		a.addNull(TagRollbackResistance, authList.RollbackResistance)
+		a.addNull(TagEarlyBootOnly, authList.EarlyBootOnly)
+		addInteger(a, TagActiveDateTime, authList.ActiveDateTime)
+		addInteger(a, TagOriginationExpireDateTime, authList.OriginationExpireDateTime)
+		addInteger(a, TagUsageExpireDateTime, authList.UsageExpireDateTime)
+		addInteger(a, TagUsageCountLimit, authList.UsageCountLimit)
+		a.addNull(TagNoAuthRequired, authList.NoAuthRequired)
+		addInteger(a, TagUserAuthType, authList.UserAuthType)
+		addInteger(a, TagAuthTimeout, authList.AuthTimeout)
+		a.addNull(TagAllowWhileOnBody, authList.AllowWhileOnBody)
+		a.addNull(TagTrustedUserPresenceRequired, authList.TrustedUserPresenceRequired)
+		a.addNull(TagTrustedConfirmationRequired, authList.TrustedConfirmationRequired)
+		a.addNull(TagUnlockedDeviceRequired, authList.UnlockedDeviceRequired)
+		a.addNull(TagAllApplications, authList.AllApplications)
+		a.addOctetString(TagApplicationId, authList.ApplicationId)
+		addInteger(a, TagCreationDateTime, authList.CreationDateTime)
+		addInteger(a, TagOrigin, authList.Origin)
+		a.addNull(TagRollbackResistant, authList.RollbackResistant)
+		if rot := authList.RootOfTrust; rot != nil {
+			a.add(TagRootOfTrust, func(b *cryptobyte.Builder) {
+				addRootOfTrust(b, rot)
+			})
+		}
+		addInteger(a, TagOsVersion, authList.OsVersion)
+		addInteger(a, TagOsPatchLevel, authList.OsPatchLevel)
+		if appId := authList.AttestationApplicationId; appId != nil {
+			a.add(TagAttestationApplicationId, func(b *cryptobyte.Builder) {
+				b.AddASN1(cryptobyte_asn1.OCTET_STRING, func(b *cryptobyte.Builder) {
+					addAttestationApplicationId(b, appId)
+				})
+			})
+		}
+		a.addOctetString(TagAttestationIdBrand, authList.AttestationIdBrand)
+		a.addOctetString(TagAttestationIdDevice, authList.AttestationIdDevice)
+		a.addOctetString(TagAttestationIdProduct, authList.AttestationIdProduct)
+		a.addOctetString(TagAttestationIdSerial, authList.AttestationIdSerial)
+		a.addOctetString(TagAttestationIdImei, authList.AttestationIdImei)
+		a.addOctetString(TagAttestationIdMeid, authList.AttestationIdMeid)
+		a.addOctetString(TagAttestationIdManufacturer, authList.AttestationIdManufacturer)
+		a.addOctetString(TagAttestationIdModel, authList.AttestationIdModel)
+		addInteger(a, TagVendorPatchLevel, authList.VendorPatchLevel)
+		addInteger(a, TagBootPatchLevel, authList.BootPatchLevel)
+		a.addNull(TagDeviceUniqueAttestation, authList.DeviceUniqueAttestation)
+		a.addNull(TagIdentityCredentialKey, authList.IdentityCredentialKey)
+		a.addOctetString(TagAttestationIdSecondImei, authList.AttestationIdSecondImei)
+		a.addOctetString(TagModuleHash, authList.ModuleHash)
+		a.addUnknown(math.MaxInt)
+	})
 }
 
-func newInt32RawValue(v *int32, tag int) (asn1.RawValue, error) {
-	if v == nil {
-		return asn1.RawValue{}, nil
-	}
-	return newAnyRawValue(*v, tag)
+// authorizationListBuilder adds the tagged values of an AuthorizationList, which must be added
+// in ascending tag order, and inserts the unknown tags in between.
+type authorizationListBuilder struct {
+	b       *cryptobyte.Builder
+	unknown []RawTag // sorted by tag
 }
 
-func newInt64RawValue(v *int64, tag int) (asn1.RawValue, error) {
-	if v == nil {
-		return asn1.RawValue{}, nil
-	}
-	return newAnyRawValue(*v, tag)
+// add adds the value of tag built by f, after the unknown tags lower than tag.
+func (a *authorizationListBuilder) add(tag int, f cryptobyte.BuilderContinuation) {
+	a.addUnknown(tag)
+	addExplicit(a.b, tag, f)
 }
 
-func newAnyRawValue(v any, tag int) (asn1.RawValue, error) {
-	derBytes, err := asn1.Marshal(v)
-	if err != nil {
-		return asn1.RawValue{}, err
+// addUnknown adds the unknown tags lower than tag.
+func (a *authorizationListBuilder) addUnknown(tag int) {
+	for len(a.unknown) > 0 && a.unknown[0].Tag < tag {
+		t := a.unknown[0]
+		a.unknown = a.unknown[1:]
+		addExplicit(a.b, t.Tag, func(b *cryptobyte.Builder) {
+			b.AddBytes(t.Value)
+		})
 	}
-	return asn1.RawValue{Class: asn1.ClassContextSpecific, Tag: tag, IsCompound: true, Bytes: derBytes}, nil
 }
 
-func newBoolRawValue(v bool, tag int) asn1.RawValue {
+func (a *authorizationListBuilder) addNull(tag int, v bool) {
 	if v {
-		return asn1.RawValue{
-			Class:      asn1.ClassContextSpecific,
-			Tag:        tag,
-			IsCompound: true,
-			Bytes:      asn1.NullBytes,
-		}
+		a.add(tag, func(b *cryptobyte.Builder) {
+			b.AddASN1NULL()
+		})
 	}
-	return asn1.RawValue{}
 }
 
-func createAuthorizationList(authList *AuthorizationList) (*authorizationList, error) {
-	var al authorizationList
-	var err error
-
-	if authList == nil {
-		return nil, errors.New("attestation: AuthorizationList is nil")
-	}
-
-	if c := authList.EcCurve; c != nil {
-		v := (int)(*c)
-		al.EcCurve, err = newAnyRawValue(v, TagEcCurve)
-		if err != nil {
-			return nil, err
-		}
-	}
-
-	al.RsaPublicExponent, err = newInt64RawValue(authList.RsaPublicExponent, TagRsaPublicExponent)
-	if err != nil {
-		return nil, err
-	}
-
-	var purposes []int32
-	for _, purpose := range authList.Purpose {
-		purposes = append(purposes, int32(purpose))
+func (a *authorizationListBuilder) addOctetString(tag int, v []byte) {
+	if v != nil {
+		a.add(tag, func(b *cryptobyte.Builder) {
+			b.AddASN1OctetString(v)
+		})
 	}
-	al.Purpose = purposes
+}
 
-	if a := authList.Algorithm; a != nil {
-		v := (int32)(*a)
-		al.Algorithm, err = newAnyRawValue(v, TagAlgorithm)
-		if err != nil {
-			return nil, err
-		}
-	}
-	al.KeySize, err = newIntRawValue(authList.KeySize, TagKeySize)
-	if err != nil {
-		return nil, err
+func addInteger[T ~int | ~int32 | ~int64 | ~uint](a *authorizationListBuilder, tag int, v *T) {
+	if v != nil {
+		a.add(tag, func(b *cryptobyte.Builder) {
+			b.AddASN1Int64(int64(*v))
+		})
 	}
+}
 
-	var digests []int
-	for _, digest := range authList.Digest {
-		digests = append(digests, int(digest))
+func addIntegerSet[T ~uint](a *authorizationListBuilder, tag int, v []T) {
+	if len(v) == 0 {
+		return
 	}
-	al.Digest = digests
 
-	var paddings []int
-	for _, padding := range authList.Padding {
-		paddings = append(paddings, int(padding))
+	elements := make([][]byte, len(v))
+	for i, e := range v {
+		b := cryptobyte.NewBuilder(nil)
+		b.AddASN1Int64(int64(e))
+		elements[i] = b.BytesOrPanic()
 	}
-	al.Padding = paddings
 
-	al.RollbackResistance = newBoolRawValue(authList.RollbackResistance, TagRollbackResistance)
-	al.ActiveDateTime, err = newInt64RawValue(authList.ActiveDateTime, TagActiveDateTime)
-	if err != nil {
-		return nil, err
-	}
-	al.OriginationExpireDateTime, err = newIntRawValue(authList.OriginationExpireDateTime, TagOriginationExpireDateTime)
-	if err != nil {
-		return nil, err
-	}
-	al.UsageExpireDateTime, err = newInt64RawValue(authList.UsageExpireDateTime, TagUsageExpireDateTime)
-	if err != nil {
-		return nil, err
-	}
-	al.NoAuthRequired = newBoolRawValue(authList.NoAuthRequired, TagNoAuthRequired)
-
-	if t := authList.UserAuthType; t != nil {
-		v := (int32)(*t)
-		al.UserAuthType, err = newAnyRawValue(v, TagUserAuthType)
-		if err != nil {
-			return nil, err
-		}
-	}
+	a.add(tag, func(b *cryptobyte.Builder) {
+		addSetOf(b, elements)
+	})
+}
 
-	al.AuthTimeout, err = newInt32RawValue(authList.AuthTimeout, TagAuthTimeout)
-	if err != nil {
-		return nil, err
-	}
-	al.AllowWhileOnBody = newBoolRawValue(authList.AllowWhileOnBody, TagAllowWhileOnBody)
-	al.TrustedUserPresenceRequired = newBoolRawValue(authList.TrustedUserPresenceRequired, TagTrustedUserPresenceRequired)
-	al.TrustedConfirmationRequired = newBoolRawValue(authList.TrustedConfirmationRequired, TagTrustedConfirmationRequired)
-	al.UnlockedDeviceRequired = newBoolRawValue(authList.UnlockedDeviceRequired, TagUnlockedDeviceRequired)
-	al.AllApplications = newBoolRawValue(authList.AllApplications, TagAllApplications)
-	al.ApplicationId = authList.ApplicationId
-	al.CreationDateTime, err = newIntRawValue(authList.CreationDateTime, TagCreationDateTime)
-	if err != nil {
-		return nil, err
-	}
+func marshalRootOfTrust(rot *RootOfTrust) ([]byte, error) {
+	b := cryptobyte.NewBuilder(nil)
+	addRootOfTrust(b, rot)
+	return b.Bytes()
+}
 
-	if o := authList.Origin; o != nil {
-		v := (int)(*o)
-		al.Origin, err = newAnyRawValue(v, TagOrigin)
-		if err != nil {
-			return nil, err
+// addRootOfTrust adds the DER encoding of rot to b. VerifiedBootHash is omitted when nil.
+func addRootOfTrust(b *cryptobyte.Builder, rot *RootOfTrust) {
+	b.AddASN1(cryptobyte_asn1.SEQUENCE, func(b *cryptobyte.Builder) {
+		b.AddASN1OctetString(rot.VerifiedBootKey)
+		b.AddASN1Boolean(rot.DeviceLocked)
+		b.AddASN1Enum(int64(rot.VerifiedBootState))
+		if rot.VerifiedBootHash != nil {
+			b.AddASN1OctetString(rot.VerifiedBootHash)
 		}
-	}
+	})
+}
 
-	al.RollbackResistant = newBoolRawValue(authList.RollbackResistant, TagRollbackResistant)
+func marshalAttestationApplicationId(appId *AttestationApplicationId) ([]byte, error) {
+	b := cryptobyte.NewBuilder(nil)
+	addAttestationApplicationId(b, appId)
+	return b.Bytes()
+}
 
-	if authList.RootOfTrust != nil {
-		rotDerBytes, err := marshalRoT(authList.RootOfTrust)
-		if err != nil {
-			return nil, err
+// addAttestationApplicationId adds the DER encoding of appId to b.
+func addAttestationApplicationId(b *cryptobyte.Builder, appId *AttestationApplicationId) {
+	packageInfos := make([][]byte, len(appId.PackageInfos))
+	for i, info := range appId.PackageInfos {
+		if info == nil {
+			b.SetError(errors.New("attestation: AttestationPackageInfo is nil"))
+			return
 		}
-		al.RootOfTrust = asn1.RawValue{Class: asn1.ClassContextSpecific, Tag: TagRootOfTrust, IsCompound: true, Bytes: rotDerBytes}
+		e := cryptobyte.NewBuilder(nil)
+		e.AddASN1(cryptobyte_asn1.SEQUENCE, func(b *cryptobyte.Builder) {
+			b.AddASN1OctetString([]byte(info.PackageName))
+			b.AddASN1Int64(int64(info.Version))
+		})
+		packageInfos[i] = e.BytesOrPanic()
 	}
 
-	al.OsVersion, err = newIntRawValue(authList.OsVersion, TagOsVersion)
-	if err != nil {
-		return nil, err
-	}
-	al.OsPatchLevel, err = newIntRawValue(authList.OsPatchLevel, TagOsPatchLevel)
-	if err != nil {
-		return nil, err
+	signatureDigests := make([][]byte, len(appId.SignatureDigests))
+	for i, digest := range appId.SignatureDigests {
+		e := cryptobyte.NewBuilder(nil)
+		e.AddASN1OctetString(digest)
+		signatureDigests[i] = e.BytesOrPanic()
 	}
 
-	if authList.AttestationApplicationId != nil {
-		appIdBytes, err := marshalAttestationApplicationId(authList.AttestationApplicationId)
-		if err != nil {
-			return nil, err
-		}
-		al.AttestationApplicationId = asn1.RawContent(appIdBytes)
-	}
-
-	al.AttestationIdBrand = authList.AttestationIdBrand
-	al.AttestationIdDevice = authList.AttestationIdDevice
-	al.AttestationIdProduct = authList.AttestationIdProduct
-	al.AttestationIdSerial = authList.AttestationIdSerial
-	al.AttestationIdImei = authList.AttestationIdImei
-	al.AttestationIdMeid = authList.AttestationIdMeid
-	al.AttestationIdManufacturer = authList.AttestationIdManufacturer
-	al.AttestationIdModel = authList.AttestationIdModel
+	b.AddASN1(cryptobyte_asn1.SEQUENCE, func(b *cryptobyte.Builder) {
+		addSetOf(b, packageInfos)
+		addSetOf(b, signatureDigests)
+	})
+}
 
-	al.VendorPatchLevel, err = newIntRawValue(authList.VendorPatchLevel, TagVendorPatchLevel)
-	if err != nil {
-		return nil, err
-	}
-	al.BootPatchLevel, err = newIntRawValue(authList.BootPatchLevel, TagBootPatchLevel)
+// CreateExtension creates a new Attestation extension based on a template.
+func CreateExtension(template *KeyDescription) (*pkix.Extension, error) {
+	derBytes, err := CreateKeyDescription(template)
 	if err != nil {
 		return nil, err
 	}
 
-	return &al, nil
+	return &pkix.Extension{
+		Id:    OIDKeyAttestationExtension,
+		Value: derBytes,
+	}, nil
 }
 
-// CreateExtension creates a new KeyDescription based on a template.
-func CreateKeyDescription(template *KeyDescription) ([]byte, error) {
-	if template == nil {
-		return nil, errors.New("attestation: template is nil")
-	}
+// ParseOptions contains parameters for ParseExtensionWithOptions.
+type ParseOptions struct {
+	// Strict rejects any encoding that is not valid DER or does not follow the KeyDescription
+	// schema. Otherwise, such anomalies are reported as warnings and parsing continues.
+	Strict bool
+}
 
-	var keyDesc keyDescription
-	keyDesc.AttestationVersion = int(template.AttestationVersion)
-	keyDesc.AttestationSecurityLevel = asn1.Enumerated(template.AttestationSecurityLevel)
-	keyDesc.KeymasterVersion = int(template.KeymasterVersion)
-	keyDesc.KeymasterSecurityLevel = asn1.Enumerated(template.KeymasterSecurityLevel)
-	keyDesc.AttestationChallenge = template.AttestationChallenge
-	keyDesc.UniqueId = template.UniqueId
+// Warning describes an anomaly found while parsing a KeyDescription in lenient mode.
+type Warning struct {
+	// Path is the field path of the anomaly, e.g. TeeEnforced.RootOfTrust.DeviceLocked.
+	Path    string
+	Message string
+}
 
-	authorizationList, err := createAuthorizationList(&template.SoftwareEnforced)
-	if err != nil {
-		return nil, err
-	}
-	keyDesc.SoftwareEnforced = asn1.RawValue{
-		Class:      asn1.ClassUniversal,
-		Tag:        asn1.TagSequence,
-		IsCompound: true,
-		Bytes:      authorizationList.Raw,
-	}
+// String returns the string representation.
+func (w Warning) String() string {
+	return w.Path + ": " + w.Message
+}
 
-	authorizationList, err = createAuthorizationList(&template.TeeEnforced)
-	if err != nil {
-		return nil, err
-	}
-	keyDesc.TeeEnforced = asn1.RawValue{
-		Class:      asn1.ClassUniversal,
-		Tag:        asn1.TagSequence,
-		IsCompound: true,
-		Bytes:      authorizationList.Raw,
-	}
+// ParseError describes a malformed field of a KeyDescription.
+type ParseError struct {
+	// Path is the field path, e.g. TeeEnforced.RootOfTrust.VerifiedBootState.
+	Path string
+	// Tag is the authorization tag of the field, or 0 outside authorization lists.
+	Tag int
+	// Offset is the byte offset of the field in KeyDescription.Raw.
+	Offset int
+	Err    error
+}
 
-	derBytes, err := asn1.Marshal(keyDesc)
-	if err != nil {
-		return nil, fmt.Errorf("attestation: %v", err)
+func (e *ParseError) Error() string {
+	if e.Tag != 0 {
+		return fmt.Sprintf("attestation: %s (tag %d) at offset %d: %v", e.Path, e.Tag, e.Offset, e.Err)
 	}
+	return fmt.Sprintf("attestation: %s at offset %d: %v", e.Path, e.Offset, e.Err)
+}
 
-	return derBytes, nil
+func (e *ParseError) Unwrap() error {
+	return e.Err
 }
 
-// CreateExtension creates a new Attestation extension based on a template.
-func CreateExtension(template *KeyDescription) (*pkix.Extension, error) {
-	derBytes, err := CreateKeyDescription(template)
-	if err != nil {
-		return nil, err
-	}
+// parser holds the state of a KeyDescription parsing.
+type parser struct {
+	opts     ParseOptions
+	warnings []Warning
+	// rejectTrailingData makes trailing data after the KeyDescription an error in lenient mode.
+	rejectTrailingData bool
+}
 
-	return &pkix.Extension{
-		Id:    OIDKeyAttestationExtension,
-		Value: derBytes,
-	}, nil
+// anomaly records an anomaly found in the field at path. It returns a *ParseError in strict
+// mode.
+func (p *parser) anomaly(path string, tag, offset int, format string, a ...any) error {
+	msg := fmt.Sprintf(format, a...)
+	if p.opts.Strict {
+		return &ParseError{Path: path, Tag: tag, Offset: offset, Err: errors.New(msg)}
+	}
+	p.warnings = append(p.warnings, Warning{Path: path, Message: msg})
+	return nil
 }
 
 // ParseExtension parses a single KeyDescription from the given ASN.1 DER data.
+//
+// Trailing data is rejected while other encoding anomalies are tolerated. Use
+// ParseExtensionWithOptions to control this behaviour and to get the anomalies.
+//
+// The returned KeyDescription references derBytes, which must not be modified.
 func ParseExtension(derBytes []byte) (*KeyDescription, error) {
-	var keyDesc keyDescription
-	if rest, err := asn1.Unmarshal(derBytes, &keyDesc); err != nil {
-		return nil, err
-	} else if len(rest) != 0 {
-		return nil, errors.New("attestation: trailing data after KeyDescription")
-	}
-	return parseKeyDescription(&keyDesc)
+	p := &parser{rejectTrailingData: true}
+	return p.parseExtension(derBytes)
 }
 
-func parseAuthorizationList(derBytes []byte) (*authorizationList, error) {
-	var authList authorizationList
-	if rest, err := asn1.Unmarshal(derBytes, &authList); err != nil {
-		return nil, err
-	} else if len(rest) != 0 {
-		return nil, errors.New("attestation: trailing data after AuthorizationList")
+// ParseExtensionWithOptions parses a single KeyDescription from the given ASN.1 DER data.
+//
+// In strict mode, any encoding that is not valid DER or out of the KeyDescription schema is
+// rejected. In lenient mode, as much as possible is parsed and each anomaly is returned as a
+// Warning.
+func ParseExtensionWithOptions(derBytes []byte, opts ParseOptions) (*KeyDescription, []Warning, error) {
+	p := &parser{opts: opts}
+	keyDesc, err := p.parseExtension(derBytes)
+	if err != nil {
+		return nil, p.warnings, err
 	}
-	return &authList, nil
+	return keyDesc, p.warnings, nil
 }
 
-func newAuthorizationList(in *authorizationList) (*AuthorizationList, error) {
-	var out = &AuthorizationList{}
-	var err error
+func (p *parser) parseExtension(derBytes []byte) (*KeyDescription, error) {
+	input := cryptobyte.String(derBytes)
+	var seq element
+	if !readElement(&input, &seq) || seq.class != asn1.ClassUniversal || seq.tag != asn1.TagSequence || !seq.constructed {
+		return nil, &ParseError{Path: "KeyDescription", Err: errors.New("malformed KeyDescription")}
+	}
+	if !input.Empty() {
+		offset := len(seq.full)
+		if p.rejectTrailingData {
+			return nil, &ParseError{Path: "KeyDescription", Offset: offset, Err: errors.New("trailing data")}
+		}
+		if err := p.anomaly("KeyDescription", 0, offset, "%d bytes of trailing data", len(input)); err != nil {
+			return nil, err
+		}
+	}
 
-	out.Raw = in.Raw
+	out := &KeyDescription{Raw: seq.full}
+	content := cryptobyte.String(seq.content)
 
-	if a, err := newOptionnalInt(in.Algorithm); err != nil {
-		return nil, err
-	} else if a != nil {
-		v := new(Algorithm)
-		*v = (Algorithm)(*a)
-		out.Algorithm = v
+	// fieldError returns an error for the field starting at field.
+	fieldError := func(name string, field cryptobyte.String) error {
+		return &ParseError{Path: name, Offset: len(seq.full) - len(field), Err: fmt.Errorf("missing or malformed %s field", name)}
 	}
 
-	if c, err := newOptionnalInt(in.EcCurve); err != nil {
-		return nil, err
-	} else if c != nil {
-		v := new(EcCurve)
-		*v = EcCurve(*c)
-		out.EcCurve = v
+	var i int64
+	var enum int
+	field := content
+	if !readASN1Int(&content, &i, strconv.IntSize) {
+		return nil, fieldError("AttestationVersion", field)
 	}
+	out.AttestationVersion = AttestationVersion(i)
 
-	out.RsaPublicExponent, err = newOptionnalInt64(in.RsaPublicExponent)
-	if err != nil {
-		return nil, err
-	}
-	out.KeySize, err = newOptionnalInt(in.KeySize)
-	if err != nil {
-		return nil, err
+	field = content
+	if !content.ReadASN1Enum(&enum) {
+		return nil, fieldError("AttestationSecurityLevel", field)
 	}
+	out.AttestationSecurityLevel = SecurityLevel(enum)
 
-	for _, d := range in.Digest {
-		out.Digest = append(out.Digest, Digest(d))
+	field = content
+	if !readASN1Int(&content, &i, strconv.IntSize) {
+		return nil, fieldError("KeymasterVersion", field)
 	}
+	out.KeymasterVersion = KeymasterVersion(i)
 
-	for _, m := range in.Padding {
-		out.Padding = append(out.Padding, PaddingMode(m))
+	field = content
+	if !content.ReadASN1Enum(&enum) {
+		return nil, fieldError("KeymasterSecurityLevel", field)
 	}
+	out.KeymasterSecurityLevel = SecurityLevel(enum)
 
-	for _, p := range in.Purpose {
-		out.Purpose = append(out.Purpose, KeyPurpose(p))
+	field = content
+	if !readASN1OctetString(&content, &out.AttestationChallenge) {
+		return nil, fieldError("AttestationChallenge", field)
 	}
 
-	out.RollbackResistance = isNullType(in.RollbackResistance)
-	out.ActiveDateTime, err = newOptionnalInt64(in.ActiveDateTime)
-	if err != nil {
-		return nil, err
+	field = content
+	if !readASN1OctetString(&content, &out.UniqueId) {
+		return nil, fieldError("UniqueId", field)
 	}
-	out.OriginationExpireDateTime, err = newOptionnalInt(in.OriginationExpireDateTime)
-	if err != nil {
-		return nil, err
-	}
-	out.UsageExpireDateTime, err = newOptionnalInt64(in.UsageExpireDateTime)
-	if err != nil {
-		return nil, err
-	}
-	out.NoAuthRequired = isNullType(in.NoAuthRequired)
 
-	if t, err := newOptionnalInt32(in.UserAuthType); err != nil {
+	var e element
+	field = content
+	if !readElement(&content, &e) {
+		return nil, fieldError("SoftwareEnforced", field)
+	}
+	if err := p.parseAuthorizationList(&out.SoftwareEnforced, "SoftwareEnforced", len(seq.full)-len(field), e.full); err != nil {
 		return nil, err
-	} else if t != nil {
-		v := new(HardwareAuthenticatorType)
-		*v = HardwareAuthenticatorType(*t)
-		out.UserAuthType = v
 	}
 
-	out.AuthTimeout, err = newOptionnalInt32(in.AuthTimeout)
-	if err != nil {
-		return nil, err
+	field = content
+	if !readElement(&content, &e) {
+		return nil, fieldError("TeeEnforced", field)
 	}
-	out.AllowWhileOnBody = isNullType(in.AllowWhileOnBody)
-	out.TrustedUserPresenceRequired = isNullType(in.TrustedUserPresenceRequired)
-	out.TrustedConfirmationRequired = isNullType(in.TrustedConfirmationRequired)
-	out.UnlockedDeviceRequired = isNullType(in.UnlockedDeviceRequired)
-	out.AllApplications = isNullType(in.AllApplications)
-	out.ApplicationId = in.ApplicationId
-	out.CreationDateTime, err = newOptionnalInt(in.CreationDateTime)
-	if err != nil {
+	if err := p.parseAuthorizationList(&out.TeeEnforced, "TeeEnforced", len(seq.full)-len(field), e.full); err != nil {
 		return nil, err
 	}
 
-	if o, err := newOptionnalInt(in.Origin); err != nil {
-		return nil, err
-	} else if o != nil {
-		v := new(KeyOrigin)
-		*v = (KeyOrigin)(*o)
-		out.Origin = v
+	if !content.Empty() {
+		if err := p.anomaly("KeyDescription", 0, len(seq.full)-len(content), "%d bytes of trailing data", len(content)); err != nil {
+			return nil, err
+		}
 	}
 
-	out.RollbackResistant = isNullType(in.RollbackResistant)
+	return out, nil
+}
 
-	if in.RootOfTrust.FullBytes != nil {
-		rot, err := parseRootOfTrust(in.RootOfTrust.Bytes)
-		if err != nil {
-			return nil, fmt.Errorf("RootOfTrust: %v", err)
-		}
-		out.RootOfTrust = rot
-	}
+// authorizationListTags maps the tags known by AuthorizationList to their field name.
+var authorizationListTags = map[int]string{
+	TagPurpose:                     "Purpose",
+	TagAlgorithm:                   "Algorithm",
+	TagKeySize:                     "KeySize",
+	TagBlockMode:                   "BlockMode",
+	TagDigest:                      "Digest",
+	TagPadding:                     "Padding",
+	TagCallerNonce:                 "CallerNonce",
+	TagMinMacLength:                "MinMacLength",
+	TagEcCurve:                     "EcCurve",
+	TagRsaPublicExponent:           "RsaPublicExponent",
+	TagMgfDigest:                   "MgfDigest",
+	TagRollbackResistance:          "RollbackResistance",
+	TagEarlyBootOnly:               "EarlyBootOnly",
+	TagActiveDateTime:              "ActiveDateTime",
+	TagOriginationExpireDateTime:   "OriginationExpireDateTime",
+	TagUsageExpireDateTime:         "UsageExpireDateTime",
+	TagUsageCountLimit:             "UsageCountLimit",
+	TagNoAuthRequired:              "NoAuthRequired",
+	TagUserAuthType:                "UserAuthType",
+	TagAuthTimeout:                 "AuthTimeout",
+	TagAllowWhileOnBody:            "AllowWhileOnBody",
+	TagTrustedUserPresenceRequired: "TrustedUserPresenceRequired",
+	TagTrustedConfirmationRequired: "TrustedConfirmationRequired",
+	TagUnlockedDeviceRequired:      "UnlockedDeviceRequired",
+	TagAllApplications:             "AllApplications",
+	TagApplicationId:               "ApplicationId",
+	TagCreationDateTime:            "CreationDateTime",
+	TagOrigin:                      "Origin",
+	TagRollbackResistant:           "RollbackResistant",
+	TagRootOfTrust:                 "RootOfTrust",
+	TagOsVersion:                   "OsVersion",
+	TagOsPatchLevel:                "OsPatchLevel",
+	TagAttestationApplicationId:    "AttestationApplicationId",
+	TagAttestationIdBrand:          "AttestationIdBrand",
+	TagAttestationIdDevice:         "AttestationIdDevice",
+	TagAttestationIdProduct:        "AttestationIdProduct",
+	TagAttestationIdSerial:         "AttestationIdSerial",
+	TagAttestationIdImei:           "AttestationIdImei",
+	TagAttestationIdMeid:           "AttestationIdMeid",
+	TagAttestationIdManufacturer:   "AttestationIdManufacturer",
+	TagAttestationIdModel:          "AttestationIdModel",
+	TagVendorPatchLevel:            "VendorPatchLevel",
+	TagBootPatchLevel:              "BootPatchLevel",
+	TagDeviceUniqueAttestation:     "DeviceUniqueAttestation",
+	TagIdentityCredentialKey:       "IdentityCredentialKey",
+	TagAttestationIdSecondImei:     "AttestationIdSecondImei",
+	TagModuleHash:                  "ModuleHash",
+}
 
-	out.OsVersion, err = newOptionnalInt(in.OsVersion)
-	if err != nil {
-		return nil, err
+// tagPath returns the field path of tag in the AuthorizationList at path.
+func tagPath(path string, tag int) string {
+	if name, ok := authorizationListTags[tag]; ok {
+		return path + "." + name
 	}
-	out.OsPatchLevel, err = newOptionnalInt(in.OsPatchLevel)
-	if err != nil {
+	return fmt.Sprintf("%s[%d]", path, tag)
+}
+
+func parseAuthorizationList(derBytes []byte) (*AuthorizationList, error) {
+	var out AuthorizationList
+	if err := (&parser{}).parseAuthorizationList(&out, "AuthorizationList", 0, derBytes); err != nil {
 		return nil, err
 	}
+	return &out, nil
+}
 
-	if in.AttestationApplicationId != nil {
-		attestationApplicationId, err := parseAttestationApplicationId(in.AttestationApplicationId)
-		if err != nil {
-			return nil, fmt.Errorf("AttestationApplicationId: %v", err)
+// parseAuthorizationList parses the AuthorizationList located at offset in KeyDescription.Raw
+// into out. Tags unknown to this package are kept in out.Unknown.
+//
+// It also checks the ordering and uniqueness of tags. In lenient mode, only the first occurrence
+// of duplicate tags is kept.
+func (p *parser) parseAuthorizationList(out *AuthorizationList, path string, offset int, derBytes []byte) error {
+	input := cryptobyte.String(derBytes)
+	var seq element
+	if !readElement(&input, &seq) || seq.class != asn1.ClassUniversal || seq.tag != asn1.TagSequence || !seq.constructed {
+		return &ParseError{Path: path, Offset: offset, Err: errors.New("malformed AuthorizationList")}
+	}
+	out.Raw = seq.full
+
+	last := -1
+	content := cryptobyte.String(seq.content)
+	for !content.Empty() {
+		elementOffset := offset + len(seq.full) - len(content)
+		previous := seq.content[:len(seq.content)-len(content)]
+		var e element
+		if !readElement(&content, &e) || e.class != asn1.ClassContextSpecific {
+			return &ParseError{Path: path, Offset: elementOffset, Err: errors.New("malformed AuthorizationList")}
 		}
 
-		app := &AttestationApplicationId{
-			SignatureDigests: attestationApplicationId.SignatureDigests,
+		// Tags in ascending order cannot be duplicates.
+		if e.tag <= last && containsTag(previous, e.tag) {
+			if err := p.anomaly(tagPath(path, e.tag), e.tag, elementOffset, "duplicate tag %d", e.tag); err != nil {
+				return err
+			}
+			continue
+		}
+		if e.tag < last {
+			if err := p.anomaly(tagPath(path, e.tag), e.tag, elementOffset, "tag %d out of order after tag %d", e.tag, last); err != nil {
+				return err
+			}
 		}
+		last = max(last, e.tag)
 
-		for _, p := range attestationApplicationId.PackageInfos {
-			pkg := &AttestationPackageInfo{
-				PackageName: string(p.PackageName),
-				Version:     p.Version,
+		if !e.constructed {
+			if err := p.anomaly(tagPath(path, e.tag), e.tag, elementOffset, "explicit tag %d is not constructed", e.tag); err != nil {
+				return err
 			}
-			app.PackageInfos = append(app.PackageInfos, pkg)
 		}
 
-		out.AttestationApplicationId = app
+		if _, ok := authorizationListTags[e.tag]; !ok {
+			out.Unknown = append(out.Unknown, RawTag{Tag: e.tag, Value: e.content})
+			continue
+		}
+
+		valueOffset := elementOffset + len(e.full) - len(e.content)
+		if err := p.parseAuthorization(out, path, e.tag, valueOffset, e.content); err != nil {
+			return err
+		}
 	}
 
-	out.AttestationIdBrand = in.AttestationIdBrand
-	out.AttestationIdDevice = in.AttestationIdDevice
-	out.AttestationIdProduct = in.AttestationIdProduct
-	out.AttestationIdSerial = in.AttestationIdSerial
-	out.AttestationIdImei = in.AttestationIdImei
-	out.AttestationIdMeid = in.AttestationIdMeid
-	out.AttestationIdManufacturer = in.AttestationIdManufacturer
-	out.AttestationIdModel = in.AttestationIdModel
-	out.VendorPatchLevel, err = newOptionnalInt(in.VendorPatchLevel)
-	if err != nil {
-		return nil, err
+	slices.SortStableFunc(out.Unknown, func(a, b RawTag) int {
+		return cmp.Compare(a.Tag, b.Tag)
+	})
+
+	return nil
+}
+
+// containsTag reports whether the encoded elements derBytes contain tag.
+func containsTag(derBytes []byte, tag int) bool {
+	input := cryptobyte.String(derBytes)
+	var e element
+	for readElement(&input, &e) {
+		if e.tag == tag {
+			return true
+		}
+	}
+	return false
+}
+
+// parseAuthorization parses the explicitly tagged value of a known tag, located at offset in
+// KeyDescription.Raw, into the matching field of out.
+func (p *parser) parseAuthorization(out *AuthorizationList, path string, tag, offset int, derBytes []byte) error {
+	var null *bool
+	switch tag {
+	case TagCallerNonce:
+		null = &out.CallerNonce
+	case TagRollbackResistance:
+		null = &out.RollbackResistance
+	case TagEarlyBootOnly:
+		null = &out.EarlyBootOnly
+	case TagNoAuthRequired:
+		null = &out.NoAuthRequired
+	case TagAllowWhileOnBody:
+		null = &out.AllowWhileOnBody
+	case TagTrustedUserPresenceRequired:
+		null = &out.TrustedUserPresenceRequired
+	case TagTrustedConfirmationRequired:
+		null = &out.TrustedConfirmationRequired
+	case TagUnlockedDeviceRequired:
+		null = &out.UnlockedDeviceRequired
+	case TagAllApplications:
+		null = &out.AllApplications
+	case TagRollbackResistant:
+		null = &out.RollbackResistant
+	case TagDeviceUniqueAttestation:
+		null = &out.DeviceUniqueAttestation
+	case TagIdentityCredentialKey:
+		null = &out.IdentityCredentialKey
+	case TagRootOfTrust:
+		rot, err := p.parseRootOfTrust(path+".RootOfTrust", offset, derBytes)
+		if err != nil {
+			return err
+		}
+		out.RootOfTrust = rot
+		return nil
+	}
+	if null != nil {
+		*null = bytes.Equal(derBytes, asn1.NullBytes)
+		if !*null {
+			return p.anomaly(tagPath(path, tag), tag, offset, "tag %d is not NULL", tag)
+		}
+		return nil
+	}
+
+	input := cryptobyte.String(derBytes)
+	sorted := true
+	var ok bool
+	var err error
+	switch tag {
+	case TagPurpose:
+		ok = readASN1IntegerSet(&input, &out.Purpose, 32, &sorted)
+	case TagAlgorithm:
+		ok = readASN1Integer(&input, &out.Algorithm, strconv.IntSize)
+	case TagKeySize:
+		ok = readASN1Integer(&input, &out.KeySize, strconv.IntSize)
+	case TagBlockMode:
+		ok = readASN1IntegerSet(&input, &out.BlockMode, strconv.IntSize, &sorted)
+	case TagDigest:
+		ok = readASN1IntegerSet(&input, &out.Digest, strconv.IntSize, &sorted)
+	case TagPadding:
+		ok = readASN1IntegerSet(&input, &out.Padding, strconv.IntSize, &sorted)
+	case TagMinMacLength:
+		ok = readASN1Integer(&input, &out.MinMacLength, strconv.IntSize)
+	case TagEcCurve:
+		ok = readASN1Integer(&input, &out.EcCurve, strconv.IntSize)
+	case TagRsaPublicExponent:
+		ok = readASN1Integer(&input, &out.RsaPublicExponent, 64)
+	case TagMgfDigest:
+		ok = readASN1IntegerSet(&input, &out.MgfDigest, strconv.IntSize, &sorted)
+	case TagActiveDateTime:
+		ok = readASN1Integer(&input, &out.ActiveDateTime, 64)
+	case TagOriginationExpireDateTime:
+		ok = readASN1Integer(&input, &out.OriginationExpireDateTime, 64)
+	case TagUsageExpireDateTime:
+		ok = readASN1Integer(&input, &out.UsageExpireDateTime, 64)
+	case TagUsageCountLimit:
+		ok = readASN1Integer(&input, &out.UsageCountLimit, strconv.IntSize)
+	case TagUserAuthType:
+		ok = readASN1Integer(&input, &out.UserAuthType, 32)
+	case TagAuthTimeout:
+		ok = readASN1Integer(&input, &out.AuthTimeout, 32)
+	case TagApplicationId:
+		ok = readASN1OctetString(&input, &out.ApplicationId)
+	case TagCreationDateTime:
+		ok = readASN1Integer(&input, &out.CreationDateTime, 64)
+	case TagOrigin:
+		ok = readASN1Integer(&input, &out.Origin, strconv.IntSize)
+	case TagOsVersion:
+		ok = readASN1Integer(&input, &out.OsVersion, strconv.IntSize)
+	case TagOsPatchLevel:
+		ok = readASN1Integer(&input, &out.OsPatchLevel, strconv.IntSize)
+	case TagAttestationApplicationId:
+		var appId []byte
+		if ok = readASN1OctetString(&input, &appId); ok {
+			out.AttestationApplicationId, err = parseAttestationApplicationId(appId)
+		}
+	case TagAttestationIdBrand:
+		ok = readASN1OctetString(&input, &out.AttestationIdBrand)
+	case TagAttestationIdDevice:
+		ok = readASN1OctetString(&input, &out.AttestationIdDevice)
+	case TagAttestationIdProduct:
+		ok = readASN1OctetString(&input, &out.AttestationIdProduct)
+	case TagAttestationIdSerial:
+		ok = readASN1OctetString(&input, &out.AttestationIdSerial)
+	case TagAttestationIdImei:
+		ok = readASN1OctetString(&input, &out.AttestationIdImei)
+	case TagAttestationIdMeid:
+		ok = readASN1OctetString(&input, &out.AttestationIdMeid)
+	case TagAttestationIdManufacturer:
+		ok = readASN1OctetString(&input, &out.AttestationIdManufacturer)
+	case TagAttestationIdModel:
+		ok = readASN1OctetString(&input, &out.AttestationIdModel)
+	case TagVendorPatchLevel:
+		ok = readASN1Integer(&input, &out.VendorPatchLevel, strconv.IntSize)
+	case TagBootPatchLevel:
+		ok = readASN1Integer(&input, &out.BootPatchLevel, strconv.IntSize)
+	case TagAttestationIdSecondImei:
+		ok = readASN1OctetString(&input, &out.AttestationIdSecondImei)
+	case TagModuleHash:
+		ok = readASN1OctetString(&input, &out.ModuleHash)
+	default:
+		return fmt.Errorf("attestation: no decoder for tag %d", tag)
+	}
+	if err == nil && !ok {
+		err = fmt.Errorf("malformed %s field", authorizationListTags[tag])
 	}
-	out.BootPatchLevel, err = newOptionnalInt(in.BootPatchLevel)
 	if err != nil {
-		return nil, err
+		return &ParseError{Path: tagPath(path, tag), Tag: tag, Offset: offset, Err: err}
 	}
 
-	return out, nil
+	if !sorted {
+		if err := p.anomaly(tagPath(path, tag), tag, offset, "SET OF elements are not sorted"); err != nil {
+			return err
+		}
+	}
+	if !input.Empty() {
+		return p.anomaly(tagPath(path, tag), tag, offset, "%d bytes of trailing data", len(input))
+	}
+
+	return nil
 }
 
-func parseKeyDescription(in *keyDescription) (*KeyDescription, error) {
-	var out = &KeyDescription{
-		Raw:                      in.Raw,
-		AttestationVersion:       AttestationVersion(in.AttestationVersion),
-		AttestationSecurityLevel: SecurityLevel(in.AttestationSecurityLevel),
-		KeymasterVersion:         KeymasterVersion(in.KeymasterVersion),
-		KeymasterSecurityLevel:   SecurityLevel(in.KeymasterSecurityLevel),
-		AttestationChallenge:     in.AttestationChallenge,
-		UniqueId:                 in.UniqueId,
+// readASN1Int reads an ASN.1 INTEGER that fits in bitSize bits into out.
+func readASN1Int(s *cryptobyte.String, out *int64, bitSize int) bool {
+	if !s.ReadASN1Int64WithTag(out, cryptobyte_asn1.INTEGER) {
+		return false
 	}
+	return bitSize >= 64 || (*out >= -1<<(bitSize-1) && *out < 1<<(bitSize-1))
+}
 
-	softwareEnforced, err := parseAuthorizationList(in.SoftwareEnforced.FullBytes)
-	if err != nil {
-		return nil, err
-	}
-	authorizationList, err := newAuthorizationList(softwareEnforced)
-	if err != nil {
-		return nil, fmt.Errorf("attestation: %v", err)
+// readASN1Integer reads an ASN.1 INTEGER that fits in bitSize bits into a new T.
+func readASN1Integer[T ~int | ~int32 | ~int64 | ~uint](s *cryptobyte.String, out **T, bitSize int) bool {
+	var i int64
+	if !readASN1Int(s, &i, bitSize) {
+		return false
 	}
-	out.SoftwareEnforced = *authorizationList
+	v := T(i)
+	*out = &v
+	return true
+}
 
-	teeEnforced, err := parseAuthorizationList(in.TeeEnforced.FullBytes)
-	if err != nil {
-		return nil, err
+// readASN1IntegerSet reads an ASN.1 SET OF INTEGER, whose elements fit in bitSize bits, into out.
+// It reports in sorted whether the elements are sorted as required by DER.
+func readASN1IntegerSet[T ~uint](s *cryptobyte.String, out *[]T, bitSize int, sorted *bool) bool {
+	var set cryptobyte.String
+	if !s.ReadASN1(&set, cryptobyte_asn1.SET) {
+		return false
 	}
-	authorizationList, err = newAuthorizationList(teeEnforced)
-	if err != nil {
-		return nil, fmt.Errorf("attestation: %v", err)
+
+	var prev cryptobyte.String
+	for !set.Empty() {
+		e := set
+		var i int64
+		if !readASN1Int(&set, &i, bitSize) {
+			return false
+		}
+		e = e[:len(e)-len(set)]
+		if prev != nil && bytes.Compare(prev, e) > 0 {
+			*sorted = false
+		}
+		prev = e
+		*out = append(*out, T(i))
 	}
-	out.TeeEnforced = *authorizationList
 
-	return out, nil
+	return true
+}
+
+// readASN1OctetString reads an ASN.1 OCTET STRING into out. out references s.
+func readASN1OctetString(s *cryptobyte.String, out *[]byte) bool {
+	var v cryptobyte.String
+	if !s.ReadASN1(&v, cryptobyte_asn1.OCTET_STRING) {
+		return false
+	}
+	*out = v
+	return true
 }
 
 func parseRootOfTrust(derBytes []byte) (*RootOfTrust, error) {
+	return (&parser{}).parseRootOfTrust("RootOfTrust", 0, derBytes)
+}
+
+// parseRootOfTrust parses the RootOfTrust located at offset in KeyDescription.Raw.
+func (p *parser) parseRootOfTrust(path string, offset int, derBytes []byte) (*RootOfTrust, error) {
 	rot := &RootOfTrust{}
 
 	input := cryptobyte.String(derBytes)
@@ -516,26 +788,45 @@ func parseRootOfTrust(derBytes []byte) (*RootOfTrust, error) {
 	// we can populate RootOfTrust.Raw, before unwrapping the
 	// SEQUENCE so it can be operated on
 	if !input.ReadASN1Element(&input, cryptobyte_asn1.SEQUENCE) {
-		return nil, errors.New("attestation: malformed RootOfTrust")
+		return nil, &ParseError{Path: path, Tag: TagRootOfTrust, Offset: offset, Err: errors.New("malformed RootOfTrust")}
 	}
 	rot.Raw = input
 	if !input.ReadASN1(&input, cryptobyte_asn1.SEQUENCE) {
-		return nil, errors.New("attestation: malformed RootOfTrust")
+		return nil, &ParseError{Path: path, Tag: TagRootOfTrust, Offset: offset, Err: errors.New("malformed RootOfTrust")}
+	}
+
+	// fieldError returns an error for the field starting at field.
+	fieldError := func(name string, field cryptobyte.String) error {
+		return &ParseError{
+			Path:   path + "." + name,
+			Tag:    TagRootOfTrust,
+			Offset: offset + len(rot.Raw) - len(field),
+			Err:    fmt.Errorf("malformed %s field", name),
+		}
 	}
 
+	field := input
 	var vbk cryptobyte.String
 	if !input.ReadASN1(&vbk, cryptobyte_asn1.OCTET_STRING) {
-		return rot, errors.New("attestation: malformed VerifiedBootKey field")
+		return rot, fieldError("VerifiedBootKey", field)
 	}
 	rot.VerifiedBootKey = vbk
 
+	field = input
 	if !readASN1Boolean(&input, &rot.DeviceLocked) {
-		return rot, errors.New("attestation: malformed DeviceLocked field")
+		return rot, fieldError("DeviceLocked", field)
+	}
+	// DER requires TRUE to be encoded as 0xff.
+	if rot.DeviceLocked && field[len(field)-len(input)-1] != 0xff {
+		if err := p.anomaly(path+".DeviceLocked", TagRootOfTrust, offset+len(rot.Raw)-len(field), "BOOLEAN is not DER encoded"); err != nil {
+			return nil, err
+		}
 	}
 
+	field = input
 	var vbs int
 	if !input.ReadASN1Enum(&vbs) {
-		return rot, errors.New("attestation: malformed VerifiedBootState field")
+		return rot, fieldError("VerifiedBootState", field)
 	}
 	rot.VerifiedBootState = VerifiedBootState(vbs)
 
@@ -545,23 +836,14 @@ func parseRootOfTrust(derBytes []byte) (*RootOfTrust, error) {
 		rot.VerifiedBootHash = vbh
 	}
 
-	return rot, nil
-}
-
-func marshalRoT(rot *RootOfTrust) (derBytes []byte, err error) {
-	rootOfTrust := rootOfTrust{
-		VerifiedBootKey:   rot.VerifiedBootKey,
-		DeviceLocked:      rot.DeviceLocked,
-		VerifiedBootState: asn1.Enumerated(rot.VerifiedBootState),
-		VerifiedBootHash:  rot.VerifiedBootHash,
-	}
-
-	derBytes, err = asn1.Marshal(rootOfTrust)
-	if err != nil {
-		return nil, fmt.Errorf("attestation: %v", err)
+	if !input.Empty() {
+		trailingOffset := offset + len(rot.Raw) - len(input)
+		if err := p.anomaly(path, TagRootOfTrust, trailingOffset, "%d bytes of trailing data", len(input)); err != nil {
+			return nil, err
+		}
 	}
 
-	return derBytes, nil
+	return rot, nil
 }
 
 func readASN1Boolean(s *cryptobyte.String, out *bool) bool {
@@ -582,39 +864,38 @@ func readASN1Boolean(s *cryptobyte.String, out *bool) bool {
 	return true
 }
 
-func parseAttestationApplicationId(derBytes []byte) (*attestationApplicationId, error) {
-	var attestAppId attestationApplicationId
-	if rest, err := asn1.Unmarshal(derBytes, &attestAppId); err != nil {
-		return nil, err
-	} else if len(rest) != 0 {
-		return nil, errors.New("attestation: trailing data after AttestationApplicationId")
-	}
-	return &attestAppId, nil
-}
-
-func marshalAttestationApplicationId(attestaAppId *AttestationApplicationId) (derBytes []byte, err error) {
-	appId := attestationApplicationId{
-		SignatureDigests: attestaAppId.SignatureDigests,
-	}
-
-	for _, p := range attestaAppId.PackageInfos {
-		pkg := attestationPackageInfo{
-			PackageName: []byte(p.PackageName),
-			Version:     p.Version,
+func parseAttestationApplicationId(derBytes []byte) (*AttestationApplicationId, error) {
+	input := cryptobyte.String(derBytes)
+	var seq, packageInfos, signatureDigests cryptobyte.String
+	if !input.ReadASN1(&seq, cryptobyte_asn1.SEQUENCE) ||
+		!seq.ReadASN1(&packageInfos, cryptobyte_asn1.SET) ||
+		!seq.ReadASN1(&signatureDigests, cryptobyte_asn1.SET) {
+		return nil, errors.New("malformed AttestationApplicationId")
+	}
+	if !input.Empty() {
+		return nil, errors.New("trailing data after AttestationApplicationId")
+	}
+
+	appId := &AttestationApplicationId{}
+	for !packageInfos.Empty() {
+		var info, name cryptobyte.String
+		var version int64
+		if !packageInfos.ReadASN1(&info, cryptobyte_asn1.SEQUENCE) ||
+			!info.ReadASN1(&name, cryptobyte_asn1.OCTET_STRING) ||
+			!readASN1Int(&info, &version, strconv.IntSize) {
+			return nil, errors.New("malformed AttestationPackageInfo")
 		}
-		appId.PackageInfos = append(appId.PackageInfos, pkg)
+		appId.PackageInfos = append(appId.PackageInfos, &AttestationPackageInfo{PackageName: string(name), Version: int(version)})
 	}
-
-	derBytes, err = asn1.Marshal(appId)
-	if err != nil {
-		return nil, fmt.Errorf("attestation: %v", err)
+	for !signatureDigests.Empty() {
+		var digest []byte
+		if !readASN1OctetString(&signatureDigests, &digest) {
+			return nil, errors.New("malformed signature digest")
+		}
+		appId.SignatureDigests = append(appId.SignatureDigests, digest)
 	}
 
-	return derBytes, nil
-}
-
-func isNullType(v asn1.RawValue) bool {
-	return bytes.Equal(v.Bytes, asn1.NullBytes)
+	return appId, nil
 }
 
 // GetKeyExtension returns the Key Attestation Extension.
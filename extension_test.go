@@ -1,22 +1,25 @@
 package attestation
 
 import (
+	"bytes"
 	"crypto/x509/pkix"
 	"encoding/asn1"
+	"errors"
 	"reflect"
+	"slices"
 	"testing"
 
 	"golang.org/x/crypto/cryptobyte"
 )
 
-func Test_createAuthorizationList(t *testing.T) {
+func Test_marshalAuthorizationList(t *testing.T) {
 	type args struct {
 		authList *AuthorizationList
 	}
 	tests := []struct {
 		name    string
 		args    args
-		want    *authorizationList
+		want    []byte
 		wantErr bool
 	}{
 		{
@@ -28,19 +31,53 @@ func Test_createAuthorizationList(t *testing.T) {
 		{
 			name:    "shouldSucceedWhenEmpty",
 			args:    args{authList: &AuthorizationList{}},
-			want:    &authorizationList{},
+			want:    []byte{0x30, 0x00},
+			wantErr: false,
+		},
+		{
+			name: "shouldSortSetOf",
+			args: args{authList: &AuthorizationList{
+				Purpose: []KeyPurpose{PurposeSign, PurposeDecrypt, 0x80},
+			}},
+			want: []byte{
+				0x30, 0x0e, // SEQUENCE
+				0xa1, 0x0c, // [1] Purpose
+				0x31, 0x0a, // SET
+				asn1.TagInteger, 0x01, byte(PurposeDecrypt),
+				asn1.TagInteger, 0x01, byte(PurposeSign),
+				asn1.TagInteger, 0x02, 0x00, 0x80,
+			},
 			wantErr: false,
 		},
+		{
+			name: "shouldFailWithKnownUnknownTag",
+			args: args{authList: &AuthorizationList{
+				Unknown: []RawTag{{Tag: TagOrigin, Value: []byte{asn1.TagInteger, 0x01, 0x00}}},
+			}},
+			want:    nil,
+			wantErr: true,
+		},
+		{
+			name: "shouldFailWithDuplicateUnknownTag",
+			args: args{authList: &AuthorizationList{
+				Unknown: []RawTag{
+					{Tag: 722, Value: []byte{asn1.TagNull, 0x00}},
+					{Tag: 722, Value: []byte{asn1.TagNull, 0x00}},
+				},
+			}},
+			want:    nil,
+			wantErr: true,
+		},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			got, err := createAuthorizationList(tt.args.authList)
+			got, err := marshalAuthorizationList(tt.args.authList)
 			if (err != nil) != tt.wantErr {
-				t.Errorf("createAuthorizationList() error = %v, wantErr %v", err, tt.wantErr)
+				t.Errorf("marshalAuthorizationList() error = %v, wantErr %v", err, tt.wantErr)
 				return
 			}
 			if !reflect.DeepEqual(got, tt.want) {
-				t.Errorf("createAuthorizationList() = %+v, want %+v", got, tt.want)
+				t.Errorf("marshalAuthorizationList() = %x, want %x", got, tt.want)
 			}
 		})
 	}
@@ -218,7 +255,7 @@ func Test_parseAuthorizationList(t *testing.T) {
 	tests := []struct {
 		name    string
 		args    args
-		want    *authorizationList
+		want    *AuthorizationList
 		wantErr bool
 	}{
 		{
@@ -236,7 +273,7 @@ func Test_parseAuthorizationList(t *testing.T) {
 		{
 			name:    "shouldSucceedWhenValidAndEmpty",
 			args:    args{derBytes: raw},
-			want:    &authorizationList{Raw: raw},
+			want:    &AuthorizationList{Raw: raw},
 			wantErr: false,
 		},
 	}
@@ -398,3 +435,410 @@ func Test_readASN1Boolean(t *testing.T) {
 		})
 	}
 }
+
+func newTestAuthorizationList() AuthorizationList {
+	algorithm := AlgoEC
+	keySize := 256
+	minMacLength := 128
+	ecCurve := CurveP256
+	rsaPublicExponent := int64(65537)
+	activeDateTime := DateTime(1700000000000)
+	originationExpireDateTime := DateTime(1800000000000)
+	usageExpireDateTime := DateTime(1900000000000)
+	usageCountLimit := 1
+	userAuthType := HwAuthTypeFingerprint
+	authTimeout := int32(300)
+	creationDateTime := DateTime(1652827723244)
+	origin := KeyOriginGenerated
+	osVersion := OsVersion(130000)
+	osPatchLevel := PatchLevel(202305)
+	vendorPatchLevel := PatchLevel(20230505)
+	bootPatchLevel := PatchLevel(20230505)
+
+	return AuthorizationList{
+		Purpose:                     []KeyPurpose{PurposeSign, PurposeVerify},
+		Algorithm:                   &algorithm,
+		KeySize:                     &keySize,
+		BlockMode:                   []BlockMode{BlockModeCBC, BlockModeGCM},
+		Digest:                      []Digest{DigestSHA_2_256, DigestSHA_2_512},
+		Padding:                     []PaddingMode{PaddingRSA_OAEP, PaddingRSA_PSS},
+		CallerNonce:                 true,
+		MinMacLength:                &minMacLength,
+		EcCurve:                     &ecCurve,
+		RsaPublicExponent:           &rsaPublicExponent,
+		MgfDigest:                   []Digest{DigestSHA1, DigestSHA_2_256},
+		RollbackResistance:          true,
+		EarlyBootOnly:               true,
+		ActiveDateTime:              &activeDateTime,
+		OriginationExpireDateTime:   &originationExpireDateTime,
+		UsageExpireDateTime:         &usageExpireDateTime,
+		UsageCountLimit:             &usageCountLimit,
+		NoAuthRequired:              true,
+		UserAuthType:                &userAuthType,
+		AuthTimeout:                 &authTimeout,
+		AllowWhileOnBody:            true,
+		TrustedUserPresenceRequired: true,
+		TrustedConfirmationRequired: true,
+		UnlockedDeviceRequired:      true,
+		AllApplications:             true,
+		ApplicationId:               []byte("application"),
+		CreationDateTime:            &creationDateTime,
+		Origin:                      &origin,
+		RollbackResistant:           true,
+		RootOfTrust: &RootOfTrust{
+			VerifiedBootKey:   []byte("key"),
+			DeviceLocked:      true,
+			VerifiedBootState: Verified,
+			VerifiedBootHash:  []byte("hash"),
+		},
+		OsVersion:    &osVersion,
+		OsPatchLevel: &osPatchLevel,
+		AttestationApplicationId: &AttestationApplicationId{
+			PackageInfos:     []*AttestationPackageInfo{{PackageName: "com.example.app", Version: 1}},
+			SignatureDigests: [][]byte{[]byte("digest")},
+		},
+		AttestationIdBrand:        []byte("brand"),
+		AttestationIdDevice:       []byte("device"),
+		AttestationIdProduct:      []byte("product"),
+		AttestationIdSerial:       []byte("serial"),
+		AttestationIdImei:         []byte("imei"),
+		AttestationIdMeid:         []byte("meid"),
+		AttestationIdManufacturer: []byte("manufacturer"),
+		AttestationIdModel:        []byte("model"),
+		VendorPatchLevel:          &vendorPatchLevel,
+		BootPatchLevel:            &bootPatchLevel,
+		DeviceUniqueAttestation:   true,
+		IdentityCredentialKey:     true,
+		AttestationIdSecondImei:   []byte("second imei"),
+		ModuleHash:                []byte("module hash"),
+	}
+}
+
+// clearRaw removes the raw encodings populated by the parser.
+func clearRaw(keyDesc *KeyDescription) {
+	keyDesc.Raw = nil
+	for _, authList := range []*AuthorizationList{&keyDesc.SoftwareEnforced, &keyDesc.TeeEnforced} {
+		authList.Raw = nil
+		if authList.RootOfTrust != nil {
+			authList.RootOfTrust.Raw = nil
+		}
+	}
+}
+
+func TestCreateKeyDescription_roundTrip(t *testing.T) {
+	tests := []struct {
+		name     string
+		template *KeyDescription
+	}{
+		{
+			name: "shouldRoundTripSoftwareEnforced",
+			template: &KeyDescription{
+				AttestationVersion:       KAKeyMintVersion3,
+				AttestationSecurityLevel: TrustedEnvironment,
+				KeymasterVersion:         KeyMintVersion3,
+				KeymasterSecurityLevel:   TrustedEnvironment,
+				AttestationChallenge:     []byte("challenge"),
+				UniqueId:                 []byte("unique"),
+				SoftwareEnforced:         newTestAuthorizationList(),
+			},
+		},
+		{
+			name: "shouldRoundTripTeeEnforced",
+			template: &KeyDescription{
+				AttestationVersion:       KAKeyMintVersion3,
+				AttestationSecurityLevel: StrongBox,
+				KeymasterVersion:         KeyMintVersion3,
+				KeymasterSecurityLevel:   StrongBox,
+				AttestationChallenge:     []byte("challenge"),
+				UniqueId:                 []byte("unique"),
+				TeeEnforced:              newTestAuthorizationList(),
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			derBytes, err := CreateKeyDescription(tt.template)
+			if err != nil {
+				t.Fatalf("CreateKeyDescription() error = %v", err)
+			}
+
+			got, err := ParseExtension(derBytes)
+			if err != nil {
+				t.Fatalf("ParseExtension() error = %v", err)
+			}
+
+			clearRaw(got)
+			if !reflect.DeepEqual(got, tt.template) {
+				t.Errorf("ParseExtension() = %+v, want %+v", got, tt.template)
+			}
+		})
+	}
+}
+
+func Test_parseAuthorizationListWithUnknown(t *testing.T) {
+	raw := []byte{
+		0x30,                   // SEQUENCE
+		0x0d,                   // LENGTH
+		0xbf, 0x85, 0x52, 0x02, // [722]
+		asn1.TagNull, 0x00,
+		0xbf, 0x85, 0x54, 0x03, // [724] ModuleHash
+		asn1.TagOctetString, 0x01, 'h',
+	}
+
+	got, err := parseAuthorizationList(raw)
+	if err != nil {
+		t.Fatalf("parseAuthorizationList() error = %v", err)
+	}
+
+	want := &AuthorizationList{
+		Raw:        raw,
+		ModuleHash: []byte{'h'},
+		Unknown:    []RawTag{{Tag: 722, Value: []byte{asn1.TagNull, 0x00}}},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("parseAuthorizationList() = %+v, want %+v", got, want)
+	}
+
+	value, err := marshalAuthorizationList(got)
+	if err != nil {
+		t.Fatalf("marshalAuthorizationList() error = %v", err)
+	}
+	if !reflect.DeepEqual(value, raw) {
+		t.Errorf("marshalAuthorizationList() = %x, want %x", value, raw)
+	}
+}
+
+// TestCreateKeyDescription_emptyOctetString checks that present but empty OCTET STRINGs survive
+// ParseExtension followed by CreateKeyDescription byte for byte.
+// TestCreateKeyDescription_primitiveUnknownTag checks that a primitive explicit tag unknown to
+// this package, accepted in lenient mode, is re-encoded as constructed.
+func TestCreateKeyDescription_primitiveUnknownTag(t *testing.T) {
+	keyDesc, warnings, err := ParseExtensionWithOptions(encodeTestKeyDescription(nil, []byte{0x9f, 0x86, 0x20, 0x01, 0x01}), ParseOptions{})
+	if err != nil {
+		t.Fatalf("ParseExtensionWithOptions() error = %v", err)
+	}
+	if len(warnings) != 1 {
+		t.Fatalf("ParseExtensionWithOptions() warnings = %v, want 1 warning", warnings)
+	}
+	want := []RawTag{{Tag: 800, Value: []byte{0x01}}}
+	if !reflect.DeepEqual(keyDesc.TeeEnforced.Unknown, want) {
+		t.Fatalf("TeeEnforced.Unknown = %v, want %v", keyDesc.TeeEnforced.Unknown, want)
+	}
+
+	got, err := CreateKeyDescription(keyDesc)
+	if err != nil {
+		t.Fatalf("CreateKeyDescription() error = %v", err)
+	}
+	if wantDER := encodeTestKeyDescription(nil, []byte{0xbf, 0x86, 0x20, 0x01, 0x01}); !bytes.Equal(got, wantDER) {
+		t.Errorf("CreateKeyDescription() = %x, want %x", got, wantDER)
+	}
+}
+
+func TestCreateKeyDescription_emptyOctetString(t *testing.T) {
+	derBytes := encodeTestKeyDescription(nil, []byte{
+		0xbf, 0x84, 0x59, 0x02, // [601] ApplicationId
+		asn1.TagOctetString, 0x00,
+		0xbf, 0x85, 0x40, 0x0d, // [704] RootOfTrust
+		0x30, 0x0b, // SEQUENCE
+		asn1.TagOctetString, 0x01, 'k', // VerifiedBootKey
+		asn1.TagBoolean, 0x01, 0xff, // DeviceLocked
+		asn1.TagEnum, 0x01, byte(Verified), // VerifiedBootState
+		asn1.TagOctetString, 0x00, // VerifiedBootHash
+	})
+
+	keyDesc, _, err := ParseExtensionWithOptions(derBytes, ParseOptions{Strict: true})
+	if err != nil {
+		t.Fatalf("ParseExtensionWithOptions() error = %v", err)
+	}
+	if v := keyDesc.TeeEnforced.ApplicationId; v == nil || len(v) != 0 {
+		t.Errorf("ApplicationId = %#v, want empty", v)
+	}
+	if v := keyDesc.TeeEnforced.RootOfTrust.VerifiedBootHash; v == nil || len(v) != 0 {
+		t.Errorf("VerifiedBootHash = %#v, want empty", v)
+	}
+
+	got, err := CreateKeyDescription(keyDesc)
+	if err != nil {
+		t.Fatalf("CreateKeyDescription() error = %v", err)
+	}
+	if !reflect.DeepEqual(got, derBytes) {
+		t.Errorf("CreateKeyDescription() = %x, want %x", got, derBytes)
+	}
+}
+
+// encodeTestKeyDescription returns a KeyDescription with the given authorization list contents.
+// The SoftwareEnforced list is located at offset 18.
+func encodeTestKeyDescription(softwareEnforced, teeEnforced []byte) []byte {
+	raw := []byte{
+		asn1.TagInteger, 0x01, byte(KAKeyMintVersion1), // AttestationVersion
+		asn1.TagEnum, 0x01, byte(TrustedEnvironment), // AttestationSecurityLevel
+		asn1.TagInteger, 0x01, byte(KeyMintVersion1), // KeymasterVersion
+		asn1.TagEnum, 0x01, byte(TrustedEnvironment), // KeymasterSecurityLevel
+		asn1.TagOctetString, 0x00, // AttestationChallenge
+		asn1.TagOctetString, 0x00, // UniqueId
+	}
+	raw = append(raw, 0x30, byte(len(softwareEnforced)))
+	raw = append(raw, softwareEnforced...)
+	raw = append(raw, 0x30, byte(len(teeEnforced)))
+	raw = append(raw, teeEnforced...)
+	return append([]byte{0x30, byte(len(raw))}, raw...)
+}
+
+func TestParseExtensionWithOptions(t *testing.T) {
+	keyDescription := func(teeEnforced ...byte) []byte {
+		return encodeTestKeyDescription(nil, teeEnforced)
+	}
+
+	purpose := []byte{0xa1, 0x05, 0x31, 0x03, asn1.TagInteger, 0x01, byte(PurposeSign)}
+	algorithm := []byte{0xa2, 0x03, asn1.TagInteger, 0x01, byte(AlgoEC)}
+	rootOfTrust := []byte{
+		0xbf, 0x85, 0x40, 0x0e, // [704] RootOfTrust
+		0x30, 0x0c,
+		asn1.TagOctetString, 0x04, 't', 'e', 's', 't', // VerifiedBootKey
+		asn1.TagBoolean, 0x01, 0x01, // DeviceLocked
+		asn1.TagEnum, 0x01, byte(Verified), // VerifiedBootState
+	}
+
+	tests := []struct {
+		name         string
+		derBytes     []byte
+		wantWarnings []Warning
+	}{
+		{
+			name:     "shouldSucceedWhenDER",
+			derBytes: keyDescription(slices.Concat(purpose, algorithm)...),
+		},
+		{
+			name:         "shouldWarnWhenTrailingData",
+			derBytes:     append(keyDescription(), 0x00),
+			wantWarnings: []Warning{{Path: "KeyDescription", Message: "1 bytes of trailing data"}},
+		},
+		{
+			name:         "shouldWarnWhenOutOfOrder",
+			derBytes:     keyDescription(slices.Concat(algorithm, purpose)...),
+			wantWarnings: []Warning{{Path: "TeeEnforced.Purpose", Message: "tag 1 out of order after tag 2"}},
+		},
+		{
+			name:         "shouldWarnWhenDuplicate",
+			derBytes:     keyDescription(slices.Concat(purpose, algorithm, algorithm)...),
+			wantWarnings: []Warning{{Path: "TeeEnforced.Algorithm", Message: "duplicate tag 2"}},
+		},
+		{
+			name:         "shouldWarnWhenPrimitive",
+			derBytes:     keyDescription(0x82, 0x03, asn1.TagInteger, 0x01, byte(AlgoEC)),
+			wantWarnings: []Warning{{Path: "TeeEnforced.Algorithm", Message: "explicit tag 2 is not constructed"}},
+		},
+		{
+			name:         "shouldWarnWhenNonStandardBool",
+			derBytes:     keyDescription(rootOfTrust...),
+			wantWarnings: []Warning{{Path: "TeeEnforced.RootOfTrust.DeviceLocked", Message: "BOOLEAN is not DER encoded"}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, warnings, err := ParseExtensionWithOptions(tt.derBytes, ParseOptions{})
+			if err != nil {
+				t.Fatalf("ParseExtensionWithOptions() error = %v", err)
+			}
+			if !reflect.DeepEqual(warnings, tt.wantWarnings) {
+				t.Errorf("ParseExtensionWithOptions() warnings = %v, want %v", warnings, tt.wantWarnings)
+			}
+			if got == nil {
+				t.Fatal("ParseExtensionWithOptions() = nil, want KeyDescription")
+			}
+
+			_, _, err = ParseExtensionWithOptions(tt.derBytes, ParseOptions{Strict: true})
+			if (err != nil) != (tt.wantWarnings != nil) {
+				t.Errorf("ParseExtensionWithOptions() strict error = %v, want error %v", err, tt.wantWarnings != nil)
+			}
+		})
+	}
+}
+
+func TestParseExtension_parseError(t *testing.T) {
+	rootOfTrust := []byte{
+		0xbf, 0x85, 0x40, 0x0e, // [704] RootOfTrust
+		0x30, 0x0c,
+		asn1.TagOctetString, 0x04, 't', 'e', 's', 't', // VerifiedBootKey
+		asn1.TagBoolean, 0x01, 0xff, // DeviceLocked
+		asn1.TagOctetString, 0x01, byte(Verified), // VerifiedBootState
+	}
+	keySize := []byte{0xa3, 0x03, asn1.TagOctetString, 0x01, 0x00}
+
+	tests := []struct {
+		name     string
+		derBytes []byte
+		want     ParseError
+	}{
+		{
+			name:     "shouldFailWhenNil",
+			derBytes: nil,
+			want:     ParseError{Path: "KeyDescription"},
+		},
+		{
+			name:     "shouldFailWhenAttestationVersionIsMalformed",
+			derBytes: []byte{0x30, 0x03, asn1.TagOctetString, 0x01, 0x00},
+			want:     ParseError{Path: "AttestationVersion", Offset: 2},
+		},
+		{
+			name:     "shouldFailWhenTrailingData",
+			derBytes: append(encodeTestKeyDescription(nil, nil), 0x00),
+			want:     ParseError{Path: "KeyDescription", Offset: 22},
+		},
+		{
+			name:     "shouldFailWhenKeySizeIsMalformed",
+			derBytes: encodeTestKeyDescription(keySize, nil),
+			want:     ParseError{Path: "SoftwareEnforced.KeySize", Tag: TagKeySize, Offset: 22},
+		},
+		{
+			name:     "shouldFailWhenVerifiedBootStateIsMalformed",
+			derBytes: encodeTestKeyDescription(nil, rootOfTrust),
+			want:     ParseError{Path: "TeeEnforced.RootOfTrust.VerifiedBootState", Tag: TagRootOfTrust, Offset: 37},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := ParseExtension(tt.derBytes)
+			var got *ParseError
+			if !errors.As(err, &got) {
+				t.Fatalf("ParseExtension() error = %v, want *ParseError", err)
+			}
+			if got.Path != tt.want.Path || got.Tag != tt.want.Tag || got.Offset != tt.want.Offset || got.Err == nil {
+				t.Errorf("ParseExtension() error = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func BenchmarkParseExtension(b *testing.B) {
+	allFields, err := CreateKeyDescription(&KeyDescription{
+		AttestationVersion:       KAKeyMintVersion3,
+		AttestationSecurityLevel: TrustedEnvironment,
+		KeymasterVersion:         KeyMintVersion3,
+		KeymasterSecurityLevel:   TrustedEnvironment,
+		AttestationChallenge:     []byte("challenge"),
+		SoftwareEnforced:         newTestAuthorizationList(),
+		TeeEnforced:              newTestAuthorizationList(),
+	})
+	if err != nil {
+		b.Fatal(err)
+	}
+
+	benchmarks := []struct {
+		name     string
+		derBytes []byte
+	}{
+		{name: "sample", derBytes: GetKeyExtension(readSample(b, "testdata/samples/keymint1-tee.pem")).Value},
+		{name: "allFields", derBytes: allFields},
+	}
+	for _, bm := range benchmarks {
+		b.Run(bm.name, func(b *testing.B) {
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				if _, err := ParseExtension(bm.derBytes); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,318 @@
+package attestation
+
+import (
+	"bytes"
+	"encoding/asn1"
+	"math/rand/v2"
+	"reflect"
+	"slices"
+	"testing"
+)
+
+func FuzzParseExtension(f *testing.F) {
+	for _, authList := range []AuthorizationList{{}, newTestAuthorizationList()} {
+		derBytes, err := CreateKeyDescription(&KeyDescription{
+			AttestationVersion:   KAKeyMintVersion3,
+			KeymasterVersion:     KeyMintVersion3,
+			AttestationChallenge: []byte("challenge"),
+			TeeEnforced:          authList,
+		})
+		if err != nil {
+			f.Fatal(err)
+		}
+		f.Add(derBytes)
+	}
+
+	f.Fuzz(func(t *testing.T, data []byte) {
+		keyDesc, err := ParseExtension(data)
+		if _, _, err := ParseExtensionWithOptions(data, ParseOptions{}); err != nil && keyDesc != nil {
+			t.Fatalf("ParseExtensionWithOptions() error = %v, ParseExtension() succeeded", err)
+		}
+		strict, _, strictErr := ParseExtensionWithOptions(data, ParseOptions{Strict: true})
+		if strictErr != nil {
+			return
+		}
+		if err != nil {
+			t.Fatalf("ParseExtension() error = %v, strict parsing succeeded", err)
+		}
+
+		// Values accepted in strict mode survive a round trip.
+		derBytes, err := CreateKeyDescription(strict)
+		if err != nil {
+			t.Fatalf("CreateKeyDescription() error = %v", err)
+		}
+		got, err := ParseExtension(derBytes)
+		if err != nil {
+			t.Fatalf("ParseExtension() error = %v", err)
+		}
+		clearRaw(got)
+		clearRaw(strict)
+		if !reflect.DeepEqual(got, strict) {
+			t.Fatalf("ParseExtension() = %+v, want %+v", got, strict)
+		}
+	})
+}
+
+func FuzzParseRootOfTrust(f *testing.F) {
+	for _, rot := range []*RootOfTrust{
+		{VerifiedBootKey: []byte("key"), DeviceLocked: true, VerifiedBootState: Verified, VerifiedBootHash: []byte("hash")},
+		{VerifiedBootKey: []byte("key"), VerifiedBootState: Unverified},
+	} {
+		derBytes, err := marshalRootOfTrust(rot)
+		if err != nil {
+			f.Fatal(err)
+		}
+		f.Add(derBytes)
+	}
+
+	f.Fuzz(func(t *testing.T, data []byte) {
+		rot, err := parseRootOfTrust(data)
+		if err != nil {
+			return
+		}
+		if !bytes.HasPrefix(data, rot.Raw) {
+			t.Fatalf("parseRootOfTrust() Raw = %x, not a prefix of %x", rot.Raw, data)
+		}
+	})
+}
+
+func FuzzParseAttestationApplicationId(f *testing.F) {
+	derBytes, err := marshalAttestationApplicationId(newTestAuthorizationList().AttestationApplicationId)
+	if err != nil {
+		f.Fatal(err)
+	}
+	f.Add(derBytes)
+
+	f.Fuzz(func(t *testing.T, data []byte) {
+		appId, err := parseAttestationApplicationId(data)
+		if err != nil {
+			return
+		}
+
+		derBytes, err := marshalAttestationApplicationId(appId)
+		if err != nil {
+			t.Fatalf("marshalAttestationApplicationId() error = %v", err)
+		}
+		if _, err := parseAttestationApplicationId(derBytes); err != nil {
+			t.Fatalf("parseAttestationApplicationId() error = %v", err)
+		}
+	})
+}
+
+// TestCreateKeyDescription_randomRoundTrip checks that random KeyDescription values survive
+// CreateKeyDescription followed by ParseExtension.
+func TestCreateKeyDescription_randomRoundTrip(t *testing.T) {
+	r := rand.New(rand.NewPCG(1, 2))
+	for i := 0; i < 500; i++ {
+		want := randomKeyDescription(r)
+
+		derBytes, err := CreateKeyDescription(want)
+		if err != nil {
+			t.Fatalf("CreateKeyDescription() error = %v, template %+v", err, want)
+		}
+
+		got, err := ParseExtension(derBytes)
+		if err != nil {
+			t.Fatalf("ParseExtension() error = %v, template %+v", err, want)
+		}
+
+		clearRaw(got)
+		if !reflect.DeepEqual(got, want) {
+			t.Fatalf("ParseExtension() = %+v, want %+v", got, want)
+		}
+	}
+}
+
+func randomKeyDescription(r *rand.Rand) *KeyDescription {
+	return &KeyDescription{
+		AttestationVersion:       AttestationVersion(r.UintN(500)),
+		AttestationSecurityLevel: SecurityLevel(r.UintN(3)),
+		KeymasterVersion:         KeymasterVersion(r.UintN(500)),
+		KeymasterSecurityLevel:   SecurityLevel(r.UintN(3)),
+		AttestationChallenge:     randomBytes(r, 1, 64),
+		UniqueId:                 randomBytes(r, 1, 16),
+		SoftwareEnforced:         randomAuthorizationList(r),
+		TeeEnforced:              randomAuthorizationList(r),
+	}
+}
+
+func randomAuthorizationList(r *rand.Rand) AuthorizationList {
+	var al AuthorizationList
+
+	// Each optional value is present with probability 1/2.
+	present := func() bool { return r.IntN(2) == 0 }
+
+	al.Purpose = randomSet[KeyPurpose](r)
+	if present() {
+		v := Algorithm(r.UintN(256))
+		al.Algorithm = &v
+	}
+	if present() {
+		v := r.IntN(1 << 16)
+		al.KeySize = &v
+	}
+	al.BlockMode = randomSet[BlockMode](r)
+	al.Digest = randomSet[Digest](r)
+	al.Padding = randomSet[PaddingMode](r)
+	al.CallerNonce = present()
+	if present() {
+		v := r.IntN(1 << 10)
+		al.MinMacLength = &v
+	}
+	if present() {
+		v := EcCurve(r.UintN(8))
+		al.EcCurve = &v
+	}
+	if present() {
+		v := r.Int64()
+		al.RsaPublicExponent = &v
+	}
+	al.MgfDigest = randomSet[Digest](r)
+	al.RollbackResistance = present()
+	al.EarlyBootOnly = present()
+	for _, v := range []**DateTime{&al.ActiveDateTime, &al.OriginationExpireDateTime, &al.UsageExpireDateTime} {
+		if present() {
+			d := DateTime(r.Int64N(1 << 42))
+			*v = &d
+		}
+	}
+	if present() {
+		v := r.IntN(1 << 16)
+		al.UsageCountLimit = &v
+	}
+	al.NoAuthRequired = present()
+	if present() {
+		v := HardwareAuthenticatorType(r.UintN(16))
+		al.UserAuthType = &v
+	}
+	if present() {
+		v := r.Int32()
+		al.AuthTimeout = &v
+	}
+	al.AllowWhileOnBody = present()
+	al.TrustedUserPresenceRequired = present()
+	al.TrustedConfirmationRequired = present()
+	al.UnlockedDeviceRequired = present()
+	al.AllApplications = present()
+	al.ApplicationId = randomBytes(r, 0, 32)
+	if present() {
+		d := DateTime(r.Int64N(1 << 42))
+		al.CreationDateTime = &d
+	}
+	if present() {
+		v := KeyOrigin(r.UintN(5))
+		al.Origin = &v
+	}
+	al.RollbackResistant = present()
+	if present() {
+		al.RootOfTrust = &RootOfTrust{
+			VerifiedBootKey:   randomBytes(r, 1, 32),
+			DeviceLocked:      present(),
+			VerifiedBootState: VerifiedBootState(r.UintN(4)),
+			VerifiedBootHash:  randomBytes(r, 0, 32),
+		}
+	}
+	if present() {
+		v := OsVersion(r.IntN(200000))
+		al.OsVersion = &v
+	}
+	if present() {
+		v := PatchLevel(r.IntN(300000))
+		al.OsPatchLevel = &v
+	}
+	if present() {
+		al.AttestationApplicationId = randomAttestationApplicationId(r)
+	}
+	for _, v := range []*[]byte{
+		&al.AttestationIdBrand, &al.AttestationIdDevice, &al.AttestationIdProduct, &al.AttestationIdSerial,
+		&al.AttestationIdImei, &al.AttestationIdMeid, &al.AttestationIdManufacturer, &al.AttestationIdModel,
+	} {
+		*v = randomBytes(r, 0, 16)
+	}
+	for _, v := range []**PatchLevel{&al.VendorPatchLevel, &al.BootPatchLevel} {
+		if present() {
+			p := PatchLevel(r.IntN(30000000))
+			*v = &p
+		}
+	}
+	al.DeviceUniqueAttestation = present()
+	al.IdentityCredentialKey = present()
+	al.AttestationIdSecondImei = randomBytes(r, 0, 16)
+	al.ModuleHash = randomBytes(r, 0, 32)
+
+	// Unknown tags are sorted and above the last known tag.
+	tag := 800
+	for n := r.IntN(3); n > 0; n-- {
+		tag += 1 + r.IntN(100)
+		value, _ := asn1.Marshal(r.Int64())
+		al.Unknown = append(al.Unknown, RawTag{Tag: tag, Value: value})
+	}
+
+	return al
+}
+
+func randomAttestationApplicationId(r *rand.Rand) *AttestationApplicationId {
+	var appId AttestationApplicationId
+	for n := r.IntN(3); n > 0; n-- {
+		appId.PackageInfos = append(appId.PackageInfos, &AttestationPackageInfo{
+			PackageName: string(randomBytes(r, 1, 32)),
+			Version:     r.IntN(1 << 30),
+		})
+	}
+	for n := r.IntN(3); n > 0; n-- {
+		appId.SignatureDigests = append(appId.SignatureDigests, randomBytes(r, 32, 32))
+	}
+
+	// SET OF elements are sorted by encoding.
+	slices.SortFunc(appId.PackageInfos, func(a, b *AttestationPackageInfo) int {
+		return bytes.Compare(marshalPackageInfo(a), marshalPackageInfo(b))
+	})
+	slices.SortFunc(appId.SignatureDigests, func(a, b []byte) int {
+		return bytes.Compare(mustMarshal(a), mustMarshal(b))
+	})
+
+	return &appId
+}
+
+// randomSet returns a random SET OF INTEGER, sorted by encoding, or nil.
+func randomSet[T ~uint](r *rand.Rand) []T {
+	var s []T
+	for n := r.IntN(4); n > 0; n-- {
+		s = append(s, T(r.UintN(300)))
+	}
+	slices.SortFunc(s, func(a, b T) int {
+		return bytes.Compare(mustMarshal(int64(a)), mustMarshal(int64(b)))
+	})
+	return s
+}
+
+// randomBytes returns between min and max random bytes. When empty, it returns either nil or an
+// empty slice, as absent and empty OCTET STRINGs are distinct.
+func randomBytes(r *rand.Rand, min, max int) []byte {
+	n := min + r.IntN(max-min+1)
+	if n == 0 && r.IntN(2) == 0 {
+		return nil
+	}
+	b := make([]byte, n)
+	for i := range b {
+		b[i] = byte(r.UintN(256))
+	}
+	return b
+}
+
+// marshalPackageInfo returns the DER encoding of an AttestationPackageInfo, independently of
+// the encoder under test.
+func marshalPackageInfo(info *AttestationPackageInfo) []byte {
+	return mustMarshal(struct {
+		PackageName []byte
+		Version     int
+	}{[]byte(info.PackageName), info.Version})
+}
+
+func mustMarshal(v any) []byte {
+	b, err := asn1.Marshal(v)
+	if err != nil {
+		panic(err)
+	}
+	return b
+}
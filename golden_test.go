@@ -0,0 +1,108 @@
+package attestation
+
+import (
+	"bytes"
+	"crypto/x509"
+	"encoding/json"
+	"encoding/pem"
+	"flag"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+var update = flag.Bool("update", false, "update the golden files in testdata/samples")
+
+// TestGoldenSamples parses the leaf certificate of each sample in testdata/samples, in PEM (.pem) or DER
+// (.der.x509) format, and compares its KeyDescription to the golden JSON file of the same name.
+func TestGoldenSamples(t *testing.T) {
+	names, err := filepath.Glob(filepath.Join("testdata", "samples", "*"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, name := range names {
+		var base string
+		switch {
+		case strings.HasSuffix(name, ".pem"):
+			base = strings.TrimSuffix(name, ".pem")
+		case strings.HasSuffix(name, ".der.x509"):
+			base = strings.TrimSuffix(name, ".der.x509")
+		default:
+			continue
+		}
+
+		t.Run(filepath.Base(base), func(t *testing.T) {
+			crt := readSample(t, name)
+
+			ext := GetKeyExtension(crt)
+			if ext == nil {
+				t.Fatal("GetKeyExtension() = nil")
+			}
+
+			keyDesc, err := ParseExtension(ext.Value)
+			if err != nil {
+				t.Fatalf("ParseExtension() error = %v", err)
+			}
+
+			// The encoder must reproduce the device encoding byte for byte.
+			derBytes, err := CreateKeyDescription(keyDesc)
+			if err != nil {
+				t.Fatalf("CreateKeyDescription() error = %v", err)
+			}
+			if !bytes.Equal(derBytes, ext.Value) {
+				t.Errorf("CreateKeyDescription() = %x, want %x", derBytes, ext.Value)
+			}
+
+			got, err := json.MarshalIndent(keyDesc, "", "  ")
+			if err != nil {
+				t.Fatal(err)
+			}
+			got = append(got, '\n')
+
+			golden := base + ".json"
+			if *update {
+				if err := os.WriteFile(golden, got, 0o644); err != nil {
+					t.Fatal(err)
+				}
+				return
+			}
+
+			want, err := os.ReadFile(golden)
+			if err != nil {
+				t.Fatalf("%v (run go test -run TestGoldenSamples -update to create it)", err)
+			}
+			if !bytes.Equal(got, want) {
+				t.Errorf("KeyDescription = %s, want %s", got, want)
+			}
+		})
+	}
+}
+
+// readSample reads the first certificate of a PEM or DER file.
+func readSample(t testing.TB, name string) *x509.Certificate {
+	t.Helper()
+
+	data, err := os.ReadFile(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if strings.HasSuffix(name, ".pem") {
+		block, _ := pem.Decode(data)
+		if block == nil || block.Type != "CERTIFICATE" {
+			t.Fatal("failed to decode certificate PEM block")
+		}
+		data = block.Bytes
+	}
+
+	crts, err := x509.ParseCertificates(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(crts) == 0 {
+		t.Fatal("no certificate found")
+	}
+	return crts[0]
+}
@@ -0,0 +1,368 @@
+package attestation
+
+import (
+	"bytes"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// The JSON representation of KeyDescription and its nested structures is described by
+// keydescription.schema.json. Fields are named after the ASN.1 schema, absent optional fields and
+// false NULL authorizations are omitted, byte strings are hex encoded, with present but empty
+// optional byte strings encoded as "", and enumerations are encoded by name. Versions, OS versions and patch levels are encoded as their ASN.1 integer value. Raw
+// encodings are not included: CreateKeyDescription followed by ParseExtension yields the same JSON,
+// except that SET OF values are listed in DER order.
+
+// hexBytes is a byte string encoded in hexadecimal.
+type hexBytes []byte
+
+// MarshalText implements the encoding.TextMarshaler interface.
+func (b hexBytes) MarshalText() ([]byte, error) {
+	return []byte(hex.EncodeToString(b)), nil
+}
+
+// UnmarshalText implements the encoding.TextUnmarshaler interface.
+func (b *hexBytes) UnmarshalText(text []byte) error {
+	v, err := hex.DecodeString(string(text))
+	if err != nil {
+		return fmt.Errorf("attestation: invalid hex string %q", text)
+	}
+	*b = v
+	return nil
+}
+
+// optionalHex returns the JSON value of an optional byte string: nil when absent, so that it is
+// omitted, and "" when present but empty.
+func optionalHex(b []byte) *hexBytes {
+	if b == nil {
+		return nil
+	}
+	v := hexBytes(b)
+	return &v
+}
+
+// bytes returns the optional byte string of a JSON value, keeping empty and absent distinct.
+func (b *hexBytes) bytes() []byte {
+	if b == nil {
+		return nil
+	}
+	if *b == nil {
+		return []byte{}
+	}
+	return *b
+}
+
+// unmarshalJSONStrict decodes data into v, rejecting unknown fields.
+func unmarshalJSONStrict(data []byte, v any) error {
+	d := json.NewDecoder(bytes.NewReader(data))
+	d.DisallowUnknownFields()
+	if err := d.Decode(v); err != nil {
+		// Errors of nested values are already prefixed.
+		if strings.HasPrefix(err.Error(), "attestation: ") {
+			return err
+		}
+		return fmt.Errorf("attestation: %v", err)
+	}
+	return nil
+}
+
+type jsonKeyDescription struct {
+	AttestationVersion       AttestationVersion `json:"attestationVersion"`
+	AttestationSecurityLevel SecurityLevel      `json:"attestationSecurityLevel"`
+	KeymasterVersion         KeymasterVersion   `json:"keymasterVersion"`
+	KeymasterSecurityLevel   SecurityLevel      `json:"keymasterSecurityLevel"`
+	AttestationChallenge     hexBytes           `json:"attestationChallenge"`
+	UniqueId                 hexBytes           `json:"uniqueId"`
+	SoftwareEnforced         AuthorizationList  `json:"softwareEnforced"`
+	TeeEnforced              AuthorizationList  `json:"teeEnforced"`
+}
+
+// MarshalJSON implements the json.Marshaler interface.
+func (k KeyDescription) MarshalJSON() ([]byte, error) {
+	return json.Marshal(jsonKeyDescription{
+		AttestationVersion:       k.AttestationVersion,
+		AttestationSecurityLevel: k.AttestationSecurityLevel,
+		KeymasterVersion:         k.KeymasterVersion,
+		KeymasterSecurityLevel:   k.KeymasterSecurityLevel,
+		AttestationChallenge:     k.AttestationChallenge,
+		UniqueId:                 k.UniqueId,
+		SoftwareEnforced:         k.SoftwareEnforced,
+		TeeEnforced:              k.TeeEnforced,
+	})
+}
+
+// UnmarshalJSON implements the json.Unmarshaler interface.
+func (k *KeyDescription) UnmarshalJSON(data []byte) error {
+	var in jsonKeyDescription
+	if err := unmarshalJSONStrict(data, &in); err != nil {
+		return err
+	}
+
+	*k = KeyDescription{
+		AttestationVersion:       in.AttestationVersion,
+		AttestationSecurityLevel: in.AttestationSecurityLevel,
+		KeymasterVersion:         in.KeymasterVersion,
+		KeymasterSecurityLevel:   in.KeymasterSecurityLevel,
+		AttestationChallenge:     in.AttestationChallenge,
+		UniqueId:                 in.UniqueId,
+		SoftwareEnforced:         in.SoftwareEnforced,
+		TeeEnforced:              in.TeeEnforced,
+	}
+	return nil
+}
+
+type jsonAuthorizationList struct {
+	Purpose                     []KeyPurpose               `json:"purpose,omitempty"`
+	Algorithm                   *Algorithm                 `json:"algorithm,omitempty"`
+	KeySize                     *int                       `json:"keySize,omitempty"`
+	BlockMode                   []BlockMode                `json:"blockMode,omitempty"`
+	Digest                      []Digest                   `json:"digest,omitempty"`
+	Padding                     []PaddingMode              `json:"padding,omitempty"`
+	CallerNonce                 bool                       `json:"callerNonce,omitempty"`
+	MinMacLength                *int                       `json:"minMacLength,omitempty"`
+	EcCurve                     *EcCurve                   `json:"ecCurve,omitempty"`
+	RsaPublicExponent           *int64                     `json:"rsaPublicExponent,omitempty"`
+	MgfDigest                   []Digest                   `json:"mgfDigest,omitempty"`
+	RollbackResistance          bool                       `json:"rollbackResistance,omitempty"`
+	EarlyBootOnly               bool                       `json:"earlyBootOnly,omitempty"`
+	ActiveDateTime              *DateTime                  `json:"activeDateTime,omitempty"`
+	OriginationExpireDateTime   *DateTime                  `json:"originationExpireDateTime,omitempty"`
+	UsageExpireDateTime         *DateTime                  `json:"usageExpireDateTime,omitempty"`
+	UsageCountLimit             *int                       `json:"usageCountLimit,omitempty"`
+	NoAuthRequired              bool                       `json:"noAuthRequired,omitempty"`
+	UserAuthType                *HardwareAuthenticatorType `json:"userAuthType,omitempty"`
+	AuthTimeout                 *int32                     `json:"authTimeout,omitempty"`
+	AllowWhileOnBody            bool                       `json:"allowWhileOnBody,omitempty"`
+	TrustedUserPresenceRequired bool                       `json:"trustedUserPresenceRequired,omitempty"`
+	TrustedConfirmationRequired bool                       `json:"trustedConfirmationRequired,omitempty"`
+	UnlockedDeviceRequired      bool                       `json:"unlockedDeviceRequired,omitempty"`
+	AllApplications             bool                       `json:"allApplications,omitempty"`
+	ApplicationId               *hexBytes                  `json:"applicationId,omitempty"`
+	CreationDateTime            *DateTime                  `json:"creationDateTime,omitempty"`
+	Origin                      *KeyOrigin                 `json:"origin,omitempty"`
+	RollbackResistant           bool                       `json:"rollbackResistant,omitempty"`
+	RootOfTrust                 *RootOfTrust               `json:"rootOfTrust,omitempty"`
+	OsVersion                   *int                       `json:"osVersion,omitempty"`
+	OsPatchLevel                *int                       `json:"osPatchLevel,omitempty"`
+	AttestationApplicationId    *AttestationApplicationId  `json:"attestationApplicationId,omitempty"`
+	AttestationIdBrand          *hexBytes                  `json:"attestationIdBrand,omitempty"`
+	AttestationIdDevice         *hexBytes                  `json:"attestationIdDevice,omitempty"`
+	AttestationIdProduct        *hexBytes                  `json:"attestationIdProduct,omitempty"`
+	AttestationIdSerial         *hexBytes                  `json:"attestationIdSerial,omitempty"`
+	AttestationIdImei           *hexBytes                  `json:"attestationIdImei,omitempty"`
+	AttestationIdMeid           *hexBytes                  `json:"attestationIdMeid,omitempty"`
+	AttestationIdManufacturer   *hexBytes                  `json:"attestationIdManufacturer,omitempty"`
+	AttestationIdModel          *hexBytes                  `json:"attestationIdModel,omitempty"`
+	VendorPatchLevel            *int                       `json:"vendorPatchLevel,omitempty"`
+	BootPatchLevel              *int                       `json:"bootPatchLevel,omitempty"`
+	DeviceUniqueAttestation     bool                       `json:"deviceUniqueAttestation,omitempty"`
+	IdentityCredentialKey       bool                       `json:"identityCredentialKey,omitempty"`
+	AttestationIdSecondImei     *hexBytes                  `json:"attestationIdSecondImei,omitempty"`
+	ModuleHash                  *hexBytes                  `json:"moduleHash,omitempty"`
+	Unknown                     []jsonRawTag               `json:"unknown,omitempty"`
+}
+
+type jsonRawTag struct {
+	Tag   int      `json:"tag"`
+	Value hexBytes `json:"value"`
+}
+
+// MarshalJSON implements the json.Marshaler interface.
+func (a AuthorizationList) MarshalJSON() ([]byte, error) {
+	out := jsonAuthorizationList{
+		Purpose:                     a.Purpose,
+		Algorithm:                   a.Algorithm,
+		KeySize:                     a.KeySize,
+		BlockMode:                   a.BlockMode,
+		Digest:                      a.Digest,
+		Padding:                     a.Padding,
+		CallerNonce:                 a.CallerNonce,
+		MinMacLength:                a.MinMacLength,
+		EcCurve:                     a.EcCurve,
+		RsaPublicExponent:           a.RsaPublicExponent,
+		MgfDigest:                   a.MgfDigest,
+		RollbackResistance:          a.RollbackResistance,
+		EarlyBootOnly:               a.EarlyBootOnly,
+		ActiveDateTime:              a.ActiveDateTime,
+		OriginationExpireDateTime:   a.OriginationExpireDateTime,
+		UsageExpireDateTime:         a.UsageExpireDateTime,
+		UsageCountLimit:             a.UsageCountLimit,
+		NoAuthRequired:              a.NoAuthRequired,
+		UserAuthType:                a.UserAuthType,
+		AuthTimeout:                 a.AuthTimeout,
+		AllowWhileOnBody:            a.AllowWhileOnBody,
+		TrustedUserPresenceRequired: a.TrustedUserPresenceRequired,
+		TrustedConfirmationRequired: a.TrustedConfirmationRequired,
+		UnlockedDeviceRequired:      a.UnlockedDeviceRequired,
+		AllApplications:             a.AllApplications,
+		ApplicationId:               optionalHex(a.ApplicationId),
+		CreationDateTime:            a.CreationDateTime,
+		Origin:                      a.Origin,
+		RollbackResistant:           a.RollbackResistant,
+		RootOfTrust:                 a.RootOfTrust,
+		OsVersion:                   (*int)(a.OsVersion),
+		OsPatchLevel:                (*int)(a.OsPatchLevel),
+		AttestationApplicationId:    a.AttestationApplicationId,
+		AttestationIdBrand:          optionalHex(a.AttestationIdBrand),
+		AttestationIdDevice:         optionalHex(a.AttestationIdDevice),
+		AttestationIdProduct:        optionalHex(a.AttestationIdProduct),
+		AttestationIdSerial:         optionalHex(a.AttestationIdSerial),
+		AttestationIdImei:           optionalHex(a.AttestationIdImei),
+		AttestationIdMeid:           optionalHex(a.AttestationIdMeid),
+		AttestationIdManufacturer:   optionalHex(a.AttestationIdManufacturer),
+		AttestationIdModel:          optionalHex(a.AttestationIdModel),
+		VendorPatchLevel:            (*int)(a.VendorPatchLevel),
+		BootPatchLevel:              (*int)(a.BootPatchLevel),
+		DeviceUniqueAttestation:     a.DeviceUniqueAttestation,
+		IdentityCredentialKey:       a.IdentityCredentialKey,
+		AttestationIdSecondImei:     optionalHex(a.AttestationIdSecondImei),
+		ModuleHash:                  optionalHex(a.ModuleHash),
+	}
+	for _, t := range a.Unknown {
+		out.Unknown = append(out.Unknown, jsonRawTag{Tag: t.Tag, Value: t.Value})
+	}
+	return json.Marshal(out)
+}
+
+// UnmarshalJSON implements the json.Unmarshaler interface.
+func (a *AuthorizationList) UnmarshalJSON(data []byte) error {
+	var in jsonAuthorizationList
+	if err := unmarshalJSONStrict(data, &in); err != nil {
+		return err
+	}
+
+	*a = AuthorizationList{
+		Purpose:                     in.Purpose,
+		Algorithm:                   in.Algorithm,
+		KeySize:                     in.KeySize,
+		BlockMode:                   in.BlockMode,
+		Digest:                      in.Digest,
+		Padding:                     in.Padding,
+		CallerNonce:                 in.CallerNonce,
+		MinMacLength:                in.MinMacLength,
+		EcCurve:                     in.EcCurve,
+		RsaPublicExponent:           in.RsaPublicExponent,
+		MgfDigest:                   in.MgfDigest,
+		RollbackResistance:          in.RollbackResistance,
+		EarlyBootOnly:               in.EarlyBootOnly,
+		ActiveDateTime:              in.ActiveDateTime,
+		OriginationExpireDateTime:   in.OriginationExpireDateTime,
+		UsageExpireDateTime:         in.UsageExpireDateTime,
+		UsageCountLimit:             in.UsageCountLimit,
+		NoAuthRequired:              in.NoAuthRequired,
+		UserAuthType:                in.UserAuthType,
+		AuthTimeout:                 in.AuthTimeout,
+		AllowWhileOnBody:            in.AllowWhileOnBody,
+		TrustedUserPresenceRequired: in.TrustedUserPresenceRequired,
+		TrustedConfirmationRequired: in.TrustedConfirmationRequired,
+		UnlockedDeviceRequired:      in.UnlockedDeviceRequired,
+		AllApplications:             in.AllApplications,
+		ApplicationId:               in.ApplicationId.bytes(),
+		CreationDateTime:            in.CreationDateTime,
+		Origin:                      in.Origin,
+		RollbackResistant:           in.RollbackResistant,
+		RootOfTrust:                 in.RootOfTrust,
+		OsVersion:                   (*OsVersion)(in.OsVersion),
+		OsPatchLevel:                (*PatchLevel)(in.OsPatchLevel),
+		AttestationApplicationId:    in.AttestationApplicationId,
+		AttestationIdBrand:          in.AttestationIdBrand.bytes(),
+		AttestationIdDevice:         in.AttestationIdDevice.bytes(),
+		AttestationIdProduct:        in.AttestationIdProduct.bytes(),
+		AttestationIdSerial:         in.AttestationIdSerial.bytes(),
+		AttestationIdImei:           in.AttestationIdImei.bytes(),
+		AttestationIdMeid:           in.AttestationIdMeid.bytes(),
+		AttestationIdManufacturer:   in.AttestationIdManufacturer.bytes(),
+		AttestationIdModel:          in.AttestationIdModel.bytes(),
+		VendorPatchLevel:            (*PatchLevel)(in.VendorPatchLevel),
+		BootPatchLevel:              (*PatchLevel)(in.BootPatchLevel),
+		DeviceUniqueAttestation:     in.DeviceUniqueAttestation,
+		IdentityCredentialKey:       in.IdentityCredentialKey,
+		AttestationIdSecondImei:     in.AttestationIdSecondImei.bytes(),
+		ModuleHash:                  in.ModuleHash.bytes(),
+	}
+	for _, t := range in.Unknown {
+		a.Unknown = append(a.Unknown, RawTag{Tag: t.Tag, Value: t.Value})
+	}
+	return nil
+}
+
+type jsonRootOfTrust struct {
+	VerifiedBootKey   hexBytes          `json:"verifiedBootKey"`
+	DeviceLocked      bool              `json:"deviceLocked"`
+	VerifiedBootState VerifiedBootState `json:"verifiedBootState"`
+	VerifiedBootHash  *hexBytes         `json:"verifiedBootHash,omitempty"`
+}
+
+// MarshalJSON implements the json.Marshaler interface.
+func (r RootOfTrust) MarshalJSON() ([]byte, error) {
+	return json.Marshal(jsonRootOfTrust{
+		VerifiedBootKey:   r.VerifiedBootKey,
+		DeviceLocked:      r.DeviceLocked,
+		VerifiedBootState: r.VerifiedBootState,
+		VerifiedBootHash:  optionalHex(r.VerifiedBootHash),
+	})
+}
+
+// UnmarshalJSON implements the json.Unmarshaler interface.
+func (r *RootOfTrust) UnmarshalJSON(data []byte) error {
+	var in jsonRootOfTrust
+	if err := unmarshalJSONStrict(data, &in); err != nil {
+		return err
+	}
+
+	*r = RootOfTrust{
+		VerifiedBootKey:   in.VerifiedBootKey,
+		DeviceLocked:      in.DeviceLocked,
+		VerifiedBootState: in.VerifiedBootState,
+		VerifiedBootHash:  in.VerifiedBootHash.bytes(),
+	}
+	return nil
+}
+
+type jsonAttestationApplicationId struct {
+	PackageInfos     []jsonAttestationPackageInfo `json:"packageInfos"`
+	SignatureDigests []hexBytes                   `json:"signatureDigests"`
+}
+
+type jsonAttestationPackageInfo struct {
+	PackageName string `json:"packageName"`
+	Version     int    `json:"version"`
+}
+
+// MarshalJSON implements the json.Marshaler interface.
+func (a AttestationApplicationId) MarshalJSON() ([]byte, error) {
+	out := jsonAttestationApplicationId{
+		PackageInfos:     []jsonAttestationPackageInfo{},
+		SignatureDigests: []hexBytes{},
+	}
+	for _, info := range a.PackageInfos {
+		if info == nil {
+			return nil, errors.New("attestation: AttestationPackageInfo is nil")
+		}
+		out.PackageInfos = append(out.PackageInfos, jsonAttestationPackageInfo{PackageName: info.PackageName, Version: info.Version})
+	}
+	for _, digest := range a.SignatureDigests {
+		out.SignatureDigests = append(out.SignatureDigests, digest)
+	}
+	return json.Marshal(out)
+}
+
+// UnmarshalJSON implements the json.Unmarshaler interface.
+func (a *AttestationApplicationId) UnmarshalJSON(data []byte) error {
+	var in jsonAttestationApplicationId
+	if err := unmarshalJSONStrict(data, &in); err != nil {
+		return err
+	}
+
+	*a = AttestationApplicationId{}
+	for _, info := range in.PackageInfos {
+		a.PackageInfos = append(a.PackageInfos, &AttestationPackageInfo{PackageName: info.PackageName, Version: info.Version})
+	}
+	for _, digest := range in.SignatureDigests {
+		a.SignatureDigests = append(a.SignatureDigests, digest)
+	}
+	return nil
+}
@@ -0,0 +1,249 @@
+package attestation
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+)
+
+func TestKeyDescription_jsonRoundTrip(t *testing.T) {
+	authList := newTestAuthorizationList()
+	authList.Unknown = []RawTag{{Tag: 800, Value: []byte{0x02, 0x01, 0x01}}}
+
+	tests := []struct {
+		name     string
+		template *KeyDescription
+	}{
+		{
+			name: "shouldRoundTripEmpty",
+			template: &KeyDescription{
+				AttestationVersion:       KAKeyMintVersion1,
+				AttestationSecurityLevel: Software,
+				KeymasterVersion:         KeyMintVersion1,
+				KeymasterSecurityLevel:   Software,
+			},
+		},
+		{
+			name: "shouldRoundTripAllFields",
+			template: &KeyDescription{
+				AttestationVersion:       KAKeyMintVersion3,
+				AttestationSecurityLevel: StrongBox,
+				KeymasterVersion:         KeyMintVersion3,
+				KeymasterSecurityLevel:   StrongBox,
+				AttestationChallenge:     []byte("challenge"),
+				UniqueId:                 []byte("unique"),
+				SoftwareEnforced:         authList,
+				TeeEnforced:              authList,
+			},
+		},
+		{
+			name: "shouldRoundTripEmptyOctetStrings",
+			template: &KeyDescription{
+				AttestationVersion:       KAKeyMintVersion3,
+				AttestationSecurityLevel: TrustedEnvironment,
+				KeymasterVersion:         KeyMintVersion3,
+				KeymasterSecurityLevel:   TrustedEnvironment,
+				TeeEnforced: AuthorizationList{
+					AttestationIdBrand: []byte{},
+					ModuleHash:         []byte{},
+					RootOfTrust:        &RootOfTrust{VerifiedBootKey: []byte{}, VerifiedBootHash: []byte{}},
+				},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			wantDER, err := CreateKeyDescription(tt.template)
+			if err != nil {
+				t.Fatalf("CreateKeyDescription() error = %v", err)
+			}
+
+			want, err := json.Marshal(tt.template)
+			if err != nil {
+				t.Fatalf("json.Marshal() error = %v", err)
+			}
+
+			var template KeyDescription
+			if err := json.Unmarshal(want, &template); err != nil {
+				t.Fatalf("json.Unmarshal() error = %v", err)
+			}
+
+			derBytes, err := CreateKeyDescription(&template)
+			if err != nil {
+				t.Fatalf("CreateKeyDescription() error = %v", err)
+			}
+			if !bytes.Equal(derBytes, wantDER) {
+				t.Errorf("CreateKeyDescription() = %x, want %x", derBytes, wantDER)
+			}
+
+			keyDesc, err := ParseExtension(derBytes)
+			if err != nil {
+				t.Fatalf("ParseExtension() error = %v", err)
+			}
+
+			got, err := json.Marshal(keyDesc)
+			if err != nil {
+				t.Fatalf("json.Marshal() error = %v", err)
+			}
+
+			if !bytes.Equal(got, want) {
+				t.Errorf("json.Marshal() = %s, want %s", got, want)
+			}
+		})
+	}
+}
+
+func TestKeyDescription_MarshalJSON(t *testing.T) {
+	algorithm := AlgoEC
+	keyDesc := KeyDescription{
+		Raw:                      []byte{0x30, 0x00},
+		AttestationVersion:       KAKeyMintVersion1,
+		AttestationSecurityLevel: TrustedEnvironment,
+		KeymasterVersion:         KeyMintVersion1,
+		KeymasterSecurityLevel:   TrustedEnvironment,
+		AttestationChallenge:     []byte("sample"),
+		TeeEnforced: AuthorizationList{
+			Purpose:        []KeyPurpose{PurposeSign},
+			Algorithm:      &algorithm,
+			NoAuthRequired: true,
+		},
+	}
+	want := `{"attestationVersion":100,"attestationSecurityLevel":"TrustedEnvironment",` +
+		`"keymasterVersion":100,"keymasterSecurityLevel":"TrustedEnvironment",` +
+		`"attestationChallenge":"73616d706c65","uniqueId":"","softwareEnforced":{},` +
+		`"teeEnforced":{"purpose":["SIGN"],"algorithm":"EC","noAuthRequired":true}}`
+
+	got, err := json.Marshal(keyDesc)
+	if err != nil {
+		t.Fatalf("json.Marshal() error = %v", err)
+	}
+	if string(got) != want {
+		t.Errorf("json.Marshal() = %s, want %s", got, want)
+	}
+}
+
+func TestKeyDescription_UnmarshalJSON(t *testing.T) {
+	tests := []struct {
+		name    string
+		data    string
+		wantErr bool
+	}{
+		{name: "shouldFailWithUnknownField", data: `{"attestationVersion":100,"foo":1}`, wantErr: true},
+		{name: "shouldFailWithUnknownAuthorization", data: `{"teeEnforced":{"foo":true}}`, wantErr: true},
+		{name: "shouldFailWithInvalidHex", data: `{"attestationChallenge":"zz"}`, wantErr: true},
+		{name: "shouldFailWithInvalidEnum", data: `{"teeEnforced":{"algorithm":"FOO"}}`, wantErr: true},
+		{name: "shouldFailWithInvalidRootOfTrust", data: `{"teeEnforced":{"rootOfTrust":{"foo":1}}}`, wantErr: true},
+		{name: "shouldFailWithInvalidDateTime", data: `{"teeEnforced":{"creationDateTime":"tomorrow"}}`, wantErr: true},
+		{name: "shouldSucceedWithUnnamedEnum", data: `{"teeEnforced":{"algorithm":"Algorithm(99)"}}`},
+		{name: "shouldSucceedWithLowercaseEnum", data: `{"attestationSecurityLevel":"strongbox"}`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var keyDesc KeyDescription
+			err := json.Unmarshal([]byte(tt.data), &keyDesc)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("json.Unmarshal() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err != nil && strings.Contains(err.Error(), "attestation: attestation:") {
+				t.Errorf("json.Unmarshal() error = %v, want a single prefix", err)
+			}
+		})
+	}
+}
+
+func TestAttestationApplicationId_MarshalJSON(t *testing.T) {
+	appId := AttestationApplicationId{PackageInfos: []*AttestationPackageInfo{nil}}
+	if _, err := json.Marshal(appId); err == nil {
+		t.Error("json.Marshal() error = nil, want error for a nil AttestationPackageInfo")
+	}
+}
+
+// TestKeyDescriptionSchema checks that the published JSON Schema matches the JSON encoding.
+func TestKeyDescriptionSchema(t *testing.T) {
+	data, err := os.ReadFile("keydescription.schema.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var schema struct {
+		Properties map[string]json.RawMessage
+		Defs       map[string]struct {
+			Properties map[string]json.RawMessage
+			AnyOf      []struct {
+				Enum []string
+			}
+		} `json:"$defs"`
+	}
+	if err := json.Unmarshal(data, &schema); err != nil {
+		t.Fatal(err)
+	}
+
+	properties := []struct {
+		name   string
+		schema map[string]json.RawMessage
+		typ    any
+	}{
+		{name: "keyDescription", schema: schema.Properties, typ: jsonKeyDescription{}},
+		{name: "authorizationList", schema: schema.Defs["authorizationList"].Properties, typ: jsonAuthorizationList{}},
+		{name: "rootOfTrust", schema: schema.Defs["rootOfTrust"].Properties, typ: jsonRootOfTrust{}},
+		{name: "attestationApplicationId", schema: schema.Defs["attestationApplicationId"].Properties, typ: jsonAttestationApplicationId{}},
+	}
+	for _, p := range properties {
+		var want []string
+		typ := reflect.TypeOf(p.typ)
+		for i := 0; i < typ.NumField(); i++ {
+			name, _, _ := strings.Cut(typ.Field(i).Tag.Get("json"), ",")
+			want = append(want, name)
+		}
+		var got []string
+		for name := range p.schema {
+			got = append(got, name)
+		}
+		sort.Strings(want)
+		sort.Strings(got)
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("%s properties = %v, want %v", p.name, got, want)
+		}
+	}
+
+	enums := []struct {
+		name  string
+		names []string
+	}{
+		{name: "securityLevel", names: mapValues(securityLevelNames)},
+		{name: "verifiedBootState", names: mapValues(verifiedBootStateNames)},
+		{name: "algorithm", names: mapValues(algorithmNames)},
+		{name: "blockMode", names: mapValues(blockModeNames)},
+		{name: "digest", names: mapValues(digestNames)},
+		{name: "ecCurve", names: mapValues(ecCurveNames)},
+		{name: "keyOrigin", names: mapValues(keyOriginNames)},
+		{name: "paddingMode", names: mapValues(paddingModeNames)},
+		{name: "keyPurpose", names: mapValues(keyPurposeNames)},
+	}
+	for _, e := range enums {
+		def := schema.Defs[e.name]
+		if len(def.AnyOf) == 0 {
+			t.Errorf("%s enum not found", e.name)
+			continue
+		}
+		got := append([]string(nil), def.AnyOf[0].Enum...)
+		sort.Strings(got)
+		if !reflect.DeepEqual(got, e.names) {
+			t.Errorf("%s enum = %v, want %v", e.name, got, e.names)
+		}
+	}
+}
+
+// mapValues returns the sorted values of m.
+func mapValues[K comparable](m map[K]string) []string {
+	var values []string
+	for _, v := range m {
+		values = append(values, v)
+	}
+	sort.Strings(values)
+	return values
+}
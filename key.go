@@ -1,37 +1,87 @@
 package attestation
 
+import (
+	"crypto/elliptic"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Algorithm specifies the cryptographic algorithm with which the key is used.
 type Algorithm uint
 
+var algorithmNames = map[Algorithm]string{
+	AlgoRSA:       "RSA",
+	AlgoEC:        "EC",
+	AlgoAES:       "AES",
+	AlgoTripleDES: "TRIPLE_DES",
+	AlgoHMAC:      "HMAC",
+}
+
+// String returns the string representation.
 func (a Algorithm) String() string {
-	switch a {
-	case AlgoRSA:
-		return "RSA"
-	case AlgoEC:
-		return "EC"
-	case AlgoAES:
-		return "AES"
-	case AlgoHMAC:
-		return "HMAC"
-	default:
-		return "unknown algorithm"
+	return enumString("Algorithm", algorithmNames, a)
+}
+
+// MarshalText implements the encoding.TextMarshaler interface.
+func (a Algorithm) MarshalText() ([]byte, error) {
+	return []byte(a.String()), nil
+}
+
+// UnmarshalText implements the encoding.TextUnmarshaler interface.
+func (a *Algorithm) UnmarshalText(text []byte) error {
+	v, err := ParseAlgorithm(string(text))
+	if err != nil {
+		return err
 	}
+	*a = v
+	return nil
+}
+
+// ParseAlgorithm parses the string representation of an Algorithm.
+func ParseAlgorithm(s string) (Algorithm, error) {
+	return parseEnum("Algorithm", algorithmNames, s)
 }
 
 const (
-	AlgoRSA  Algorithm = 1
-	AlgoEC   Algorithm = 3
-	AlgoAES  Algorithm = 32
-	AlgoHMAC Algorithm = 128
+	AlgoRSA       Algorithm = 1
+	AlgoEC        Algorithm = 3
+	AlgoAES       Algorithm = 32
+	AlgoTripleDES Algorithm = 33
+	AlgoHMAC      Algorithm = 128
 )
 
 // KeyBlobUsageRequirements specifies the necessary system environment conditions for the generated key to be used.
 type KeyBlobUsageRequirements uint
 
+var keyBlobUsageRequirementsNames = map[KeyBlobUsageRequirements]string{
+	KBURequirementsStandalone:         "STANDALONE",
+	KBURequirementsRequiresFileSystem: "REQUIRES_FILE_SYSTEM",
+}
+
+// String returns the string representation.
 func (r KeyBlobUsageRequirements) String() string {
-	return [...]string{
-		"STANDALONE",
-		"REQUIRES_FILE_SYSTEM",
-	}[r]
+	return enumString("KeyBlobUsageRequirements", keyBlobUsageRequirementsNames, r)
+}
+
+// MarshalText implements the encoding.TextMarshaler interface.
+func (r KeyBlobUsageRequirements) MarshalText() ([]byte, error) {
+	return []byte(r.String()), nil
+}
+
+// UnmarshalText implements the encoding.TextUnmarshaler interface.
+func (r *KeyBlobUsageRequirements) UnmarshalText(text []byte) error {
+	v, err := ParseKeyBlobUsageRequirements(string(text))
+	if err != nil {
+		return err
+	}
+	*r = v
+	return nil
+}
+
+// ParseKeyBlobUsageRequirements parses the string representation of a KeyBlobUsageRequirements.
+func ParseKeyBlobUsageRequirements(s string) (KeyBlobUsageRequirements, error) {
+	return parseEnum("KeyBlobUsageRequirements", keyBlobUsageRequirementsNames, s)
 }
 
 const (
@@ -42,17 +92,40 @@ const (
 // BlockMode specifies the block cipher mode(s) with which the key may be used. This tag is only relevant to AES keys.
 type BlockMode uint
 
+var blockModeNames = map[BlockMode]string{
+	BlockModeECB: "ECB",
+	BlockModeCBC: "CBC",
+	BlockModeCTR: "CTR",
+	BlockModeGCM: "GCM",
+}
+
+// String returns the string representation.
 func (m BlockMode) String() string {
-	return [...]string{
-		"ECB",
-		"CBC",
-		"CTR",
-		"GCM",
-	}[m]
+	return enumString("BlockMode", blockModeNames, m)
+}
+
+// MarshalText implements the encoding.TextMarshaler interface.
+func (m BlockMode) MarshalText() ([]byte, error) {
+	return []byte(m.String()), nil
+}
+
+// UnmarshalText implements the encoding.TextUnmarshaler interface.
+func (m *BlockMode) UnmarshalText(text []byte) error {
+	v, err := ParseBlockMode(string(text))
+	if err != nil {
+		return err
+	}
+	*m = v
+	return nil
+}
+
+// ParseBlockMode parses the string representation of a BlockMode.
+func ParseBlockMode(s string) (BlockMode, error) {
+	return parseEnum("BlockMode", blockModeNames, s)
 }
 
 const (
-	BlockModeECB BlockMode = iota
+	BlockModeECB BlockMode = iota + 1
 	BlockModeCBC
 	BlockModeCTR
 	BlockModeGCM BlockMode = 32
@@ -61,16 +134,39 @@ const (
 // Digest specifies the digest algorithms that may be used with the key to perform signing and verification operations. This tag is relevant to RSA, ECDSA and HMAC keys.
 type Digest uint
 
+var digestNames = map[Digest]string{
+	DigestNONE:      "NONE",
+	DigestMD5:       "MD5",
+	DigestSHA1:      "SHA1",
+	DigestSHA_2_224: "SHA_2_224",
+	DigestSHA_2_256: "SHA_2_256",
+	DigestSHA_2_384: "SHA_2_384",
+	DigestSHA_2_512: "SHA_2_512",
+}
+
+// String returns the string representation.
 func (d Digest) String() string {
-	return [...]string{
-		"NONE",
-		"MD5",
-		"SHA1",
-		"SHA_2_224",
-		"SHA_2_256",
-		"SHA_2_384",
-		"SHA_2_512",
-	}[d]
+	return enumString("Digest", digestNames, d)
+}
+
+// MarshalText implements the encoding.TextMarshaler interface.
+func (d Digest) MarshalText() ([]byte, error) {
+	return []byte(d.String()), nil
+}
+
+// UnmarshalText implements the encoding.TextUnmarshaler interface.
+func (d *Digest) UnmarshalText(text []byte) error {
+	v, err := ParseDigest(string(text))
+	if err != nil {
+		return err
+	}
+	*d = v
+	return nil
+}
+
+// ParseDigest parses the string representation of a Digest.
+func ParseDigest(s string) (Digest, error) {
+	return parseEnum("Digest", digestNames, s)
 }
 
 const (
@@ -86,13 +182,52 @@ const (
 // EcCurve specifies the EC curves.
 type EcCurve uint
 
+var ecCurveNames = map[EcCurve]string{
+	CurveP224:  "P_224",
+	CurveP256:  "P_256",
+	CurveP384:  "P_384",
+	CurveP521:  "P_521",
+	Curve25519: "CURVE_25519",
+}
+
+// String returns the string representation.
 func (c EcCurve) String() string {
-	return [...]string{
-		"P_224",
-		"P_256",
-		"P_384",
-		"P_521",
-	}[c]
+	return enumString("EcCurve", ecCurveNames, c)
+}
+
+// MarshalText implements the encoding.TextMarshaler interface.
+func (c EcCurve) MarshalText() ([]byte, error) {
+	return []byte(c.String()), nil
+}
+
+// UnmarshalText implements the encoding.TextUnmarshaler interface.
+func (c *EcCurve) UnmarshalText(text []byte) error {
+	v, err := ParseEcCurve(string(text))
+	if err != nil {
+		return err
+	}
+	*c = v
+	return nil
+}
+
+// ParseEcCurve parses the string representation of a EcCurve.
+func ParseEcCurve(s string) (EcCurve, error) {
+	return parseEnum("EcCurve", ecCurveNames, s)
+}
+
+// ecCurves maps the supported elliptic curves to their EcCurve.
+var ecCurves = map[elliptic.Curve]EcCurve{
+	elliptic.P224(): CurveP224,
+	elliptic.P256(): CurveP256,
+	elliptic.P384(): CurveP384,
+	elliptic.P521(): CurveP521,
+}
+
+// EcCurveOf returns the EcCurve of an elliptic curve. It reports false if the curve is not one
+// of the NIST curves P-224, P-256, P-384 or P-521.
+func EcCurveOf(curve elliptic.Curve) (EcCurve, bool) {
+	c, ok := ecCurves[curve]
+	return c, ok
 }
 
 const (
@@ -100,39 +235,88 @@ const (
 	CurveP256
 	CurveP384
 	CurveP521
+	Curve25519
 )
 
 // KeyOrigin specifies where the key was created, if known.
 type KeyOrigin uint
 
+var keyOriginNames = map[KeyOrigin]string{
+	KeyOriginGenerated:        "GENERATED",
+	KeyOriginDerived:          "DERIVED",
+	KeyOriginImported:         "IMPORTED",
+	KeyOriginUnknown:          "UNKNOWN",
+	KeyOriginSecurelyImported: "SECURELY_IMPORTED",
+}
+
+// String returns the string representation.
 func (o KeyOrigin) String() string {
-	return [...]string{
-		"GENERATED",
-		"DERIVED",
-		"IMPORTED",
-		"UNKNOWN",
-	}[o]
+	return enumString("KeyOrigin", keyOriginNames, o)
+}
+
+// MarshalText implements the encoding.TextMarshaler interface.
+func (o KeyOrigin) MarshalText() ([]byte, error) {
+	return []byte(o.String()), nil
+}
+
+// UnmarshalText implements the encoding.TextUnmarshaler interface.
+func (o *KeyOrigin) UnmarshalText(text []byte) error {
+	v, err := ParseKeyOrigin(string(text))
+	if err != nil {
+		return err
+	}
+	*o = v
+	return nil
+}
+
+// ParseKeyOrigin parses the string representation of a KeyOrigin.
+func ParseKeyOrigin(s string) (KeyOrigin, error) {
+	return parseEnum("KeyOrigin", keyOriginNames, s)
 }
 
 const (
 	KeyOriginGenerated KeyOrigin = iota
 	KeyOriginDerived
 	KeyOriginImported
-	KeyOriginUnknown
+	KeyOriginUnknown // Reserved in KeyMint.
+	KeyOriginSecurelyImported
 )
 
 // PaddingMode specifies the padding modes that may be used with the key.
 type PaddingMode uint
 
+var paddingModeNames = map[PaddingMode]string{
+	PaddingNone:                  "NONE",
+	PaddingRSA_OAEP:              "RSA_OAEP",
+	PaddingRSA_PSS:               "RSA_PSS",
+	PaddingRSA_PKCS1_1_5_ENCRYPT: "RSA_PKCS1_1_5_ENCRYPT",
+	PaddingRSA_PKCS1_1_5_SIGN:    "RSA_PKCS1_1_5_SIGN",
+	PaddingPKCS7:                 "PKCS7",
+}
+
+// String returns the string representation.
 func (m PaddingMode) String() string {
-	return [...]string{
-		"NONE",
-		"RSA_OAEP",
-		"RSA_PSS",
-		"RSA_PKCS1_1_5_ENCRYPT",
-		"RSA_PKCS1_1_5_SIGN",
-		"PKCS7",
-	}[m]
+	return enumString("PaddingMode", paddingModeNames, m)
+}
+
+// MarshalText implements the encoding.TextMarshaler interface.
+func (m PaddingMode) MarshalText() ([]byte, error) {
+	return []byte(m.String()), nil
+}
+
+// UnmarshalText implements the encoding.TextUnmarshaler interface.
+func (m *PaddingMode) UnmarshalText(text []byte) error {
+	v, err := ParsePaddingMode(string(text))
+	if err != nil {
+		return err
+	}
+	*m = v
+	return nil
+}
+
+// ParsePaddingMode parses the string representation of a PaddingMode.
+func ParsePaddingMode(s string) (PaddingMode, error) {
+	return parseEnum("PaddingMode", paddingModeNames, s)
 }
 
 const (
@@ -148,15 +332,40 @@ const (
 // KeyPurpose specifies the set of purposes for which the key may be used.
 type KeyPurpose uint
 
+var keyPurposeNames = map[KeyPurpose]string{
+	PurposeEncrypt:   "ENCRYPT",
+	PurposeDecrypt:   "DECRYPT",
+	PurposeSign:      "SIGN",
+	PurposeVerify:    "VERIFY",
+	PurposeDeriveKey: "DERIVE_KEY",
+	PurposeWrapKey:   "WRAP_KEY",
+	PurposeAgreeKey:  "AGREE_KEY",
+	PurposeAttestKey: "ATTEST_KEY",
+}
+
+// String returns the string representation.
 func (p KeyPurpose) String() string {
-	return [...]string{
-		"ENCRYPT",
-		"DECRYPT",
-		"SIGN",
-		"VERIFY",
-		"DERIVE_KEY",
-		"WRAP_KEY",
-	}[p]
+	return enumString("KeyPurpose", keyPurposeNames, p)
+}
+
+// MarshalText implements the encoding.TextMarshaler interface.
+func (p KeyPurpose) MarshalText() ([]byte, error) {
+	return []byte(p.String()), nil
+}
+
+// UnmarshalText implements the encoding.TextUnmarshaler interface.
+func (p *KeyPurpose) UnmarshalText(text []byte) error {
+	v, err := ParseKeyPurpose(string(text))
+	if err != nil {
+		return err
+	}
+	*p = v
+	return nil
+}
+
+// ParseKeyPurpose parses the string representation of a KeyPurpose.
+func ParseKeyPurpose(s string) (KeyPurpose, error) {
+	return parseEnum("KeyPurpose", keyPurposeNames, s)
 }
 
 const (
@@ -164,25 +373,99 @@ const (
 	PurposeDecrypt
 	PurposeSign
 	PurposeVerify
-	PurposeDeriveKey
+	PurposeDeriveKey // Keymaster only.
 	PurposeWrapKey
+	PurposeAgreeKey
+	PurposeAttestKey
 )
 
 // HardwareAuthenticatorType specifies the types of user authenticators that may be used to authorize this key.
+//
+// It is a set of flags, e.g. HwAuthTypePassword|HwAuthTypeFingerprint.
 type HardwareAuthenticatorType uint
 
+var hardwareAuthenticatorTypeNames = map[HardwareAuthenticatorType]string{
+	HwAuthTypeNone:        "NONE",
+	HwAuthTypePassword:    "PASSWORD",
+	HwAuthTypeFingerprint: "FINGERPRINT",
+	HwAuthTypeAny:         "ANY",
+}
+
+// Has reports whether all the flags of flag are set in t. HwAuthTypeNone is only had by
+// HwAuthTypeNone.
+func (t HardwareAuthenticatorType) Has(flag HardwareAuthenticatorType) bool {
+	if flag == HwAuthTypeNone {
+		return t == HwAuthTypeNone
+	}
+	return t&flag == flag
+}
+
+// Flags returns the individual flags set in t, in increasing bit order.
+func (t HardwareAuthenticatorType) Flags() []HardwareAuthenticatorType {
+	var flags []HardwareAuthenticatorType
+	for bit := HardwareAuthenticatorType(1); bit != 0 && bit <= t; bit <<= 1 {
+		if t&bit != 0 {
+			flags = append(flags, bit)
+		}
+	}
+	return flags
+}
+
+// String returns the string representation, e.g. PASSWORD|FINGERPRINT. Unknown flags are
+// represented in hexadecimal.
 func (t HardwareAuthenticatorType) String() string {
-	return [...]string{
-		"NONE",
-		"PASSWORD",
-		"FINGERPRINT",
-		"ANY",
-	}[t]
+	if name, ok := hardwareAuthenticatorTypeNames[t]; ok {
+		return name
+	}
+
+	var names []string
+	for _, flag := range t.Flags() {
+		name, ok := hardwareAuthenticatorTypeNames[flag]
+		if !ok {
+			name = fmt.Sprintf("0x%x", uint(flag))
+		}
+		names = append(names, name)
+	}
+	return strings.Join(names, "|")
+}
+
+// MarshalText implements the encoding.TextMarshaler interface.
+func (t HardwareAuthenticatorType) MarshalText() ([]byte, error) {
+	return []byte(t.String()), nil
+}
+
+// UnmarshalText implements the encoding.TextUnmarshaler interface.
+func (t *HardwareAuthenticatorType) UnmarshalText(text []byte) error {
+	v, err := ParseHardwareAuthenticatorType(string(text))
+	if err != nil {
+		return err
+	}
+	*t = v
+	return nil
+}
+
+// ParseHardwareAuthenticatorType parses the string representation of a HardwareAuthenticatorType,
+// i.e. flag names or hexadecimal values separated by |.
+func ParseHardwareAuthenticatorType(s string) (HardwareAuthenticatorType, error) {
+	var t HardwareAuthenticatorType
+	for _, part := range strings.Split(s, "|") {
+		part = strings.TrimSpace(part)
+		if v, err := strconv.ParseUint(part, 0, 32); err == nil && strings.HasPrefix(part, "0x") {
+			t |= HardwareAuthenticatorType(v)
+			continue
+		}
+		v, err := parseEnum("HardwareAuthenticatorType", hardwareAuthenticatorTypeNames, part)
+		if err != nil {
+			return 0, fmt.Errorf("attestation: invalid HardwareAuthenticatorType %q", s)
+		}
+		t |= v
+	}
+	return t, nil
 }
 
 const (
-	HwAuthTypeNone     HardwareAuthenticatorType = iota
-	HwAuthTypePassword HardwareAuthenticatorType = 1 << iota
-	HwAuthTypeFingerprint
-	HwAuthTypeAny HardwareAuthenticatorType = HardwareAuthenticatorType(^uint32(0))
+	HwAuthTypeNone        HardwareAuthenticatorType = 0
+	HwAuthTypePassword    HardwareAuthenticatorType = 1 << 0
+	HwAuthTypeFingerprint HardwareAuthenticatorType = 1 << 1
+	HwAuthTypeAny         HardwareAuthenticatorType = HardwareAuthenticatorType(^uint32(0))
 )
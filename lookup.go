@@ -0,0 +1,199 @@
+package attestation
+
+import "slices"
+
+// Value is the value of an authorization tag, as returned by AuthorizationList.Get.
+//
+// Its dynamic type is the type of the AuthorizationList field of the tag, with scalar pointers
+// dereferenced: []KeyPurpose for TagPurpose, Algorithm for TagAlgorithm, int for TagKeySize,
+// []byte for OCTET_STRING tags, *RootOfTrust for TagRootOfTrust and so on. NULL tags, such as
+// TagNoAuthRequired, have the value true. Tags unknown to this package have a RawTag value.
+type Value any
+
+// knownTags lists the tags known by AuthorizationList in ascending order.
+var knownTags = func() []int {
+	tags := make([]int, 0, len(authorizationListTags))
+	for tag := range authorizationListTags {
+		tags = append(tags, tag)
+	}
+	slices.Sort(tags)
+	return tags
+}()
+
+// Has reports whether tag is present in the AuthorizationList.
+func (l *AuthorizationList) Has(tag int) bool {
+	_, ok := l.Get(tag)
+	return ok
+}
+
+// Get returns the value of tag and whether it is present in the AuthorizationList. Nil values,
+// empty sets and false NULL tags are not present, as they are not encoded. An empty but non-nil
+// OCTET STRING is present.
+func (l *AuthorizationList) Get(tag int) (Value, bool) {
+	switch tag {
+	case TagPurpose:
+		return sliceValue(l.Purpose)
+	case TagAlgorithm:
+		return ptrValue(l.Algorithm)
+	case TagKeySize:
+		return ptrValue(l.KeySize)
+	case TagBlockMode:
+		return sliceValue(l.BlockMode)
+	case TagDigest:
+		return sliceValue(l.Digest)
+	case TagPadding:
+		return sliceValue(l.Padding)
+	case TagCallerNonce:
+		return nullValue(l.CallerNonce)
+	case TagMinMacLength:
+		return ptrValue(l.MinMacLength)
+	case TagEcCurve:
+		return ptrValue(l.EcCurve)
+	case TagRsaPublicExponent:
+		return ptrValue(l.RsaPublicExponent)
+	case TagMgfDigest:
+		return sliceValue(l.MgfDigest)
+	case TagRollbackResistance:
+		return nullValue(l.RollbackResistance)
+	case TagEarlyBootOnly:
+		return nullValue(l.EarlyBootOnly)
+	case TagActiveDateTime:
+		return ptrValue(l.ActiveDateTime)
+	case TagOriginationExpireDateTime:
+		return ptrValue(l.OriginationExpireDateTime)
+	case TagUsageExpireDateTime:
+		return ptrValue(l.UsageExpireDateTime)
+	case TagUsageCountLimit:
+		return ptrValue(l.UsageCountLimit)
+	case TagNoAuthRequired:
+		return nullValue(l.NoAuthRequired)
+	case TagUserAuthType:
+		return ptrValue(l.UserAuthType)
+	case TagAuthTimeout:
+		return ptrValue(l.AuthTimeout)
+	case TagAllowWhileOnBody:
+		return nullValue(l.AllowWhileOnBody)
+	case TagTrustedUserPresenceRequired:
+		return nullValue(l.TrustedUserPresenceRequired)
+	case TagTrustedConfirmationRequired:
+		return nullValue(l.TrustedConfirmationRequired)
+	case TagUnlockedDeviceRequired:
+		return nullValue(l.UnlockedDeviceRequired)
+	case TagAllApplications:
+		return nullValue(l.AllApplications)
+	case TagApplicationId:
+		return bytesValue(l.ApplicationId)
+	case TagCreationDateTime:
+		return ptrValue(l.CreationDateTime)
+	case TagOrigin:
+		return ptrValue(l.Origin)
+	case TagRollbackResistant:
+		return nullValue(l.RollbackResistant)
+	case TagRootOfTrust:
+		return structValue(l.RootOfTrust)
+	case TagOsVersion:
+		return ptrValue(l.OsVersion)
+	case TagOsPatchLevel:
+		return ptrValue(l.OsPatchLevel)
+	case TagAttestationApplicationId:
+		return structValue(l.AttestationApplicationId)
+	case TagAttestationIdBrand:
+		return bytesValue(l.AttestationIdBrand)
+	case TagAttestationIdDevice:
+		return bytesValue(l.AttestationIdDevice)
+	case TagAttestationIdProduct:
+		return bytesValue(l.AttestationIdProduct)
+	case TagAttestationIdSerial:
+		return bytesValue(l.AttestationIdSerial)
+	case TagAttestationIdImei:
+		return bytesValue(l.AttestationIdImei)
+	case TagAttestationIdMeid:
+		return bytesValue(l.AttestationIdMeid)
+	case TagAttestationIdManufacturer:
+		return bytesValue(l.AttestationIdManufacturer)
+	case TagAttestationIdModel:
+		return bytesValue(l.AttestationIdModel)
+	case TagVendorPatchLevel:
+		return ptrValue(l.VendorPatchLevel)
+	case TagBootPatchLevel:
+		return ptrValue(l.BootPatchLevel)
+	case TagDeviceUniqueAttestation:
+		return nullValue(l.DeviceUniqueAttestation)
+	case TagIdentityCredentialKey:
+		return nullValue(l.IdentityCredentialKey)
+	case TagAttestationIdSecondImei:
+		return bytesValue(l.AttestationIdSecondImei)
+	case TagModuleHash:
+		return bytesValue(l.ModuleHash)
+	}
+
+	for _, t := range l.Unknown {
+		if t.Tag == tag {
+			return t, true
+		}
+	}
+	return nil, false
+}
+
+// Tags returns the tags present in the AuthorizationList, including unknown tags, in ascending
+// order. It returns a slice rather than an iter.Seq because the module supports Go 1.22, which
+// cannot range over functions.
+func (l *AuthorizationList) Tags() []int {
+	var tags []int
+	for _, tag := range knownTags {
+		if l.Has(tag) {
+			tags = append(tags, tag)
+		}
+	}
+	for _, t := range l.Unknown {
+		tags = append(tags, t.Tag)
+	}
+	slices.Sort(tags)
+	return slices.Compact(tags)
+}
+
+// Lookup returns the value of tag in the hardware-enforced list of the KeyDescription, or in the
+// software-enforced list when not hardware-enforced. hardwareEnforced reports which list the
+// value comes from, and ok whether tag is present in either list.
+func (k *KeyDescription) Lookup(tag int) (v Value, hardwareEnforced bool, ok bool) {
+	if v, ok := k.TeeEnforced.Get(tag); ok {
+		return v, true, true
+	}
+	v, ok = k.SoftwareEnforced.Get(tag)
+	return v, false, ok
+}
+
+func ptrValue[T any](v *T) (Value, bool) {
+	if v == nil {
+		return nil, false
+	}
+	return *v, true
+}
+
+func structValue[T any](v *T) (Value, bool) {
+	if v == nil {
+		return nil, false
+	}
+	return v, true
+}
+
+func sliceValue[T any](v []T) (Value, bool) {
+	if len(v) == 0 {
+		return nil, false
+	}
+	return v, true
+}
+
+func bytesValue(v []byte) (Value, bool) {
+	if v == nil {
+		return nil, false
+	}
+	return v, true
+}
+
+func nullValue(v bool) (Value, bool) {
+	if !v {
+		return nil, false
+	}
+	return true, true
+}
@@ -0,0 +1,99 @@
+package attestation
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestAuthorizationList_Get(t *testing.T) {
+	algorithm := AlgoEC
+	keySize := 256
+	rot := &RootOfTrust{DeviceLocked: true}
+	authList := &AuthorizationList{
+		Purpose:        []KeyPurpose{PurposeSign},
+		Algorithm:      &algorithm,
+		KeySize:        &keySize,
+		NoAuthRequired: true,
+		RootOfTrust:    rot,
+		Digest:         []Digest{},
+		ApplicationId:  []byte{},
+		Unknown:        []RawTag{{Tag: 722, Value: []byte{0x05, 0x00}}},
+	}
+
+	tests := []struct {
+		name   string
+		tag    int
+		want   Value
+		wantOk bool
+	}{
+		{name: "shouldSucceedWithSet", tag: TagPurpose, want: []KeyPurpose{PurposeSign}, wantOk: true},
+		{name: "shouldSucceedWithEnum", tag: TagAlgorithm, want: AlgoEC, wantOk: true},
+		{name: "shouldSucceedWithInteger", tag: TagKeySize, want: 256, wantOk: true},
+		{name: "shouldSucceedWithNull", tag: TagNoAuthRequired, want: true, wantOk: true},
+		{name: "shouldSucceedWithRootOfTrust", tag: TagRootOfTrust, want: rot, wantOk: true},
+		{name: "shouldSucceedWithUnknown", tag: 722, want: RawTag{Tag: 722, Value: []byte{0x05, 0x00}}, wantOk: true},
+		{name: "shouldFailWhenNil", tag: TagEcCurve},
+		{name: "shouldFailWhenFalse", tag: TagCallerNonce},
+		{name: "shouldSucceedWithEmptyOctetString", tag: TagApplicationId, want: []byte{}, wantOk: true},
+		{name: "shouldFailWhenEmptySet", tag: TagDigest},
+		{name: "shouldFailWhenNilOctetString", tag: TagModuleHash},
+		{name: "shouldFailWhenNilStruct", tag: TagAttestationApplicationId},
+		{name: "shouldFailWhenMissingUnknown", tag: 9999},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := authList.Get(tt.tag)
+			if ok != tt.wantOk || !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Get() = %v, %v, want %v, %v", got, ok, tt.want, tt.wantOk)
+			}
+			if has := authList.Has(tt.tag); has != tt.wantOk {
+				t.Errorf("Has() = %v, want %v", has, tt.wantOk)
+			}
+		})
+	}
+
+	want := []int{TagPurpose, TagAlgorithm, TagKeySize, TagNoAuthRequired, TagApplicationId, TagRootOfTrust, 722}
+	if got := authList.Tags(); !reflect.DeepEqual(got, want) {
+		t.Errorf("Tags() = %v, want %v", got, want)
+	}
+}
+
+// TestAuthorizationList_Tags checks that Tags, and so Get, report every tag known by
+// AuthorizationList.
+func TestAuthorizationList_Tags(t *testing.T) {
+	authList := newTestAuthorizationList()
+	if got := authList.Tags(); !reflect.DeepEqual(got, knownTags) {
+		t.Errorf("Tags() = %v, want %v", got, knownTags)
+	}
+	if got := (&AuthorizationList{}).Tags(); got != nil {
+		t.Errorf("Tags() = %v, want nil", got)
+	}
+}
+
+func TestKeyDescription_Lookup(t *testing.T) {
+	osVersion := OsVersion(130000)
+	keyDesc := &KeyDescription{
+		SoftwareEnforced: AuthorizationList{CreationDateTime: new(DateTime), OsVersion: new(OsVersion)},
+		TeeEnforced:      AuthorizationList{OsVersion: &osVersion},
+	}
+
+	tests := []struct {
+		name             string
+		tag              int
+		want             Value
+		hardwareEnforced bool
+		ok               bool
+	}{
+		{name: "shouldPreferHardwareEnforced", tag: TagOsVersion, want: osVersion, hardwareEnforced: true, ok: true},
+		{name: "shouldSucceedWithSoftwareEnforced", tag: TagCreationDateTime, want: DateTime(0), ok: true},
+		{name: "shouldFailWhenMissing", tag: TagOrigin},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, hardwareEnforced, ok := keyDesc.Lookup(tt.tag)
+			if !reflect.DeepEqual(got, tt.want) || hardwareEnforced != tt.hardwareEnforced || ok != tt.ok {
+				t.Errorf("Lookup() = %v, %v, %v, want %v, %v, %v", got, hardwareEnforced, ok, tt.want, tt.hardwareEnforced, tt.ok)
+			}
+		})
+	}
+}
@@ -0,0 +1,151 @@
+package attestation
+
+import (
+	"cmp"
+	"fmt"
+	"time"
+)
+
+// OsVersion is the version of the operating system, encoded as MMmmss, e.g. 130000 for
+// version 13.0.0.
+type OsVersion int
+
+// Major returns the major version.
+func (v OsVersion) Major() int {
+	return int(v) / 10000
+}
+
+// Minor returns the minor version.
+func (v OsVersion) Minor() int {
+	return int(v) / 100 % 100
+}
+
+// SubMinor returns the sub-minor version.
+func (v OsVersion) SubMinor() int {
+	return int(v) % 100
+}
+
+// Valid reports whether v is a valid operating system version. Zero, which means the version
+// is unknown, is valid.
+func (v OsVersion) Valid() bool {
+	return v >= 0 && v <= 999999
+}
+
+// Compare returns -1, 0 or +1 depending on whether v is older, equal or newer than o.
+func (v OsVersion) Compare(o OsVersion) int {
+	return cmp.Compare(v, o)
+}
+
+// Before reports whether v is older than o.
+func (v OsVersion) Before(o OsVersion) bool {
+	return v.Compare(o) < 0
+}
+
+// After reports whether v is newer than o.
+func (v OsVersion) After(o OsVersion) bool {
+	return v.Compare(o) > 0
+}
+
+// String returns the string representation, e.g. 13.0.0.
+func (v OsVersion) String() string {
+	if !v.Valid() {
+		return fmt.Sprintf("OsVersion(%d)", int(v))
+	}
+	return fmt.Sprintf("%d.%d.%d", v.Major(), v.Minor(), v.SubMinor())
+}
+
+// PatchLevel is a security patch level, encoded either as YYYYMM (OsPatchLevel) or as
+// YYYYMMDD (VendorPatchLevel and BootPatchLevel).
+type PatchLevel int
+
+// HasDay reports whether p is encoded as YYYYMMDD.
+func (p PatchLevel) HasDay() bool {
+	return p > 999999
+}
+
+// Year returns the year.
+func (p PatchLevel) Year() int {
+	if p.HasDay() {
+		return int(p) / 10000
+	}
+	return int(p) / 100
+}
+
+// Month returns the month.
+func (p PatchLevel) Month() time.Month {
+	if p.HasDay() {
+		return time.Month(int(p) / 100 % 100)
+	}
+	return time.Month(int(p) % 100)
+}
+
+// Day returns the day of the month, or 0 if p is encoded as YYYYMM.
+func (p PatchLevel) Day() int {
+	if p.HasDay() {
+		return int(p) % 100
+	}
+	return 0
+}
+
+// Valid reports whether p is a valid YYYYMM or YYYYMMDD date.
+func (p PatchLevel) Valid() bool {
+	if p < 100001 || p > 99991231 || (p > 999999 && p < 10000101) {
+		return false
+	}
+	if p.Month() < time.January || p.Month() > time.December {
+		return false
+	}
+	if !p.HasDay() {
+		return true
+	}
+	// time.Date normalizes out-of-range days into the following month.
+	t := time.Date(p.Year(), p.Month(), p.Day(), 0, 0, 0, 0, time.UTC)
+	return p.Day() >= 1 && t.Month() == p.Month()
+}
+
+// Time returns the date of p at midnight UTC. The first day of the month is used when p is
+// encoded as YYYYMM.
+func (p PatchLevel) Time() time.Time {
+	return time.Date(p.Year(), p.Month(), max(p.Day(), 1), 0, 0, 0, 0, time.UTC)
+}
+
+// Compare returns -1, 0 or +1 depending on whether p is older, equal or newer than o. Patch
+// levels of the same month are equal when either of them is encoded as YYYYMM.
+func (p PatchLevel) Compare(o PatchLevel) int {
+	if c := cmp.Compare(p.Year(), o.Year()); c != 0 {
+		return c
+	}
+	if c := cmp.Compare(p.Month(), o.Month()); c != 0 {
+		return c
+	}
+	if !p.HasDay() || !o.HasDay() {
+		return 0
+	}
+	return cmp.Compare(p.Day(), o.Day())
+}
+
+// Before reports whether p is older than o.
+func (p PatchLevel) Before(o PatchLevel) bool {
+	return p.Compare(o) < 0
+}
+
+// After reports whether p is newer than o.
+func (p PatchLevel) After(o PatchLevel) bool {
+	return p.Compare(o) > 0
+}
+
+// Age returns the time elapsed between p and t.
+func (p PatchLevel) Age(t time.Time) time.Duration {
+	return t.Sub(p.Time())
+}
+
+// String returns the string representation, e.g. 2023-05 or 2023-05-05.
+func (p PatchLevel) String() string {
+	if !p.Valid() {
+		return fmt.Sprintf("PatchLevel(%d)", int(p))
+	}
+	if p.HasDay() {
+		return fmt.Sprintf("%04d-%02d-%02d", p.Year(), p.Month(), p.Day())
+	}
+	return fmt.Sprintf("%04d-%02d", p.Year(), p.Month())
+}
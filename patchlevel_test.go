@@ -0,0 +1,117 @@
+package attestation
+
+import (
+	"testing"
+	"time"
+)
+
+func TestOsVersion(t *testing.T) {
+	tests := []struct {
+		name      string
+		v         OsVersion
+		want      string
+		wantValid bool
+	}{
+		{name: "shouldSucceedWithUnknown", v: 0, want: "0.0.0", wantValid: true},
+		{name: "shouldSucceedWithMajor", v: 130000, want: "13.0.0", wantValid: true},
+		{name: "shouldSucceedWithMinor", v: 80102, want: "8.1.2", wantValid: true},
+		{name: "shouldFailWhenNegative", v: -1, want: "OsVersion(-1)"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.v.Valid(); got != tt.wantValid {
+				t.Errorf("Valid() = %v, want %v", got, tt.wantValid)
+			}
+			if got := tt.v.String(); got != tt.want {
+				t.Errorf("String() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+
+	if !OsVersion(120000).Before(130000) || !OsVersion(130000).After(120100) {
+		t.Error("OsVersion comparison is not consistent with versions")
+	}
+}
+
+func TestPatchLevel(t *testing.T) {
+	tests := []struct {
+		name      string
+		p         PatchLevel
+		want      string
+		wantValid bool
+		wantTime  time.Time
+	}{
+		{
+			name:      "shouldSucceedWithMonth",
+			p:         202305,
+			want:      "2023-05",
+			wantValid: true,
+			wantTime:  time.Date(2023, time.May, 1, 0, 0, 0, 0, time.UTC),
+		},
+		{
+			name:      "shouldSucceedWithDay",
+			p:         20230505,
+			want:      "2023-05-05",
+			wantValid: true,
+			wantTime:  time.Date(2023, time.May, 5, 0, 0, 0, 0, time.UTC),
+		},
+		{name: "shouldFailWhenZero", p: 0, want: "PatchLevel(0)"},
+		{name: "shouldFailWhenMonthIsInvalid", p: 202313, want: "PatchLevel(202313)"},
+		{name: "shouldFailWhenDayIsInvalid", p: 20230230, want: "PatchLevel(20230230)"},
+		{name: "shouldFailWhenDayIsZero", p: 20230500, want: "PatchLevel(20230500)"},
+		{name: "shouldFailWhenTooShort", p: 2023051, want: "PatchLevel(2023051)"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.p.Valid(); got != tt.wantValid {
+				t.Errorf("Valid() = %v, want %v", got, tt.wantValid)
+			}
+			if got := tt.p.String(); got != tt.want {
+				t.Errorf("String() = %q, want %q", got, tt.want)
+			}
+			if tt.wantValid && !tt.p.Time().Equal(tt.wantTime) {
+				t.Errorf("Time() = %v, want %v", tt.p.Time(), tt.wantTime)
+			}
+		})
+	}
+}
+
+func TestPatchLevel_Compare(t *testing.T) {
+	tests := []struct {
+		name string
+		p, o PatchLevel
+		want int
+	}{
+		{name: "shouldCompareMonths", p: 202304, o: 202305, want: -1},
+		{name: "shouldCompareDays", p: 20230505, o: 20230501, want: 1},
+		{name: "shouldCompareAcrossForms", p: 202304, o: 20230505, want: -1},
+		{name: "shouldCompareAcrossYears", p: 20221231, o: 202301, want: -1},
+		{name: "shouldBeEqualWithinMonth", p: 202305, o: 20230505, want: 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.p.Compare(tt.o); got != tt.want {
+				t.Errorf("Compare() = %d, want %d", got, tt.want)
+			}
+			if got := tt.o.Compare(tt.p); got != -tt.want {
+				t.Errorf("Compare() = %d, want %d", got, -tt.want)
+			}
+			if got := tt.p.Before(tt.o); got != (tt.want < 0) {
+				t.Errorf("Before() = %v, want %v", got, tt.want < 0)
+			}
+			if got := tt.p.After(tt.o); got != (tt.want > 0) {
+				t.Errorf("After() = %v, want %v", got, tt.want > 0)
+			}
+		})
+	}
+}
+
+func TestPatchLevel_Age(t *testing.T) {
+	now := time.Date(2023, time.June, 5, 0, 0, 0, 0, time.UTC)
+	if got, want := PatchLevel(20230505).Age(now), 31*24*time.Hour; got != want {
+		t.Errorf("Age() = %v, want %v", got, want)
+	}
+	if got, want := PatchLevel(202306).Age(now), 4*24*time.Hour; got != want {
+		t.Errorf("Age() = %v, want %v", got, want)
+	}
+}
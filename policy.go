@@ -0,0 +1,456 @@
+package attestation
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"slices"
+	"strings"
+)
+
+// Policy is a set of rules a KeyDescription must satisfy.
+//
+// A policy can be loaded from JSON with ParsePolicy. Each rule is an object whose "type" member
+// selects the rule kind:
+//
+//	{
+//		"rules": [
+//			{"type": "securityLevel", "levels": ["TrustedEnvironment", "StrongBox"]},
+//			{"type": "deviceLocked"},
+//			{"type": "verifiedBootState", "states": ["Verified"]},
+//			{"type": "minimum", "field": "osPatchLevel", "value": 202301},
+//			{"type": "any", "rules": [
+//				{"type": "packageName", "names": ["com.example.app"]},
+//				{"type": "not", "rule": {"type": "deviceLocked"}}
+//			]}
+//		]
+//	}
+type Policy struct {
+	Rules []Rule
+}
+
+// Rule is a single verification rule.
+type Rule interface {
+	// Name returns a short description of the rule.
+	Name() string
+	// Evaluate evaluates the rule against a KeyDescription.
+	Evaluate(keyDesc *KeyDescription) RuleResult
+}
+
+// RuleResult is the outcome of a rule evaluation.
+type RuleResult struct {
+	Rule     string
+	Passed   bool
+	Reason   string
+	Children []RuleResult `json:",omitempty"`
+}
+
+// PolicyResult is the outcome of a policy evaluation.
+type PolicyResult struct {
+	Passed  bool
+	Results []RuleResult
+}
+
+// Failed returns the results of the top-level rules that did not pass.
+func (r PolicyResult) Failed() []RuleResult {
+	var failed []RuleResult
+	for _, res := range r.Results {
+		if !res.Passed {
+			failed = append(failed, res)
+		}
+	}
+	return failed
+}
+
+// Evaluate evaluates every rule of the policy against a KeyDescription. The policy passes if all
+// its rules pass.
+func (p *Policy) Evaluate(keyDesc *KeyDescription) PolicyResult {
+	result := PolicyResult{Passed: true}
+	for _, rule := range p.Rules {
+		res := evaluate(rule, keyDesc)
+		result.Passed = result.Passed && res.Passed
+		result.Results = append(result.Results, res)
+	}
+	return result
+}
+
+func evaluate(rule Rule, keyDesc *KeyDescription) RuleResult {
+	if keyDesc == nil {
+		return RuleResult{Rule: rule.Name(), Reason: "no KeyDescription"}
+	}
+	return rule.Evaluate(keyDesc)
+}
+
+// ParsePolicy parses a policy from its JSON representation. Unknown members, policies without
+// rules and incomplete rules are rejected, so that a mistake in a policy does not let every
+// KeyDescription pass.
+func ParsePolicy(r io.Reader) (*Policy, error) {
+	var p Policy
+	if err := json.NewDecoder(r).Decode(&p); err != nil {
+		return nil, err
+	}
+	return &p, nil
+}
+
+// UnmarshalJSON implements the json.Unmarshaler interface.
+func (p *Policy) UnmarshalJSON(data []byte) error {
+	var in struct {
+		Rules []json.RawMessage `json:"rules"`
+	}
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&in); err != nil {
+		return fmt.Errorf("attestation: %v", err)
+	}
+
+	rules, err := unmarshalRules(in.Rules)
+	if err != nil {
+		return fmt.Errorf("attestation: %v", err)
+	}
+	p.Rules = rules
+
+	return nil
+}
+
+func unmarshalRules(in []json.RawMessage) ([]Rule, error) {
+	if len(in) == 0 {
+		return nil, errors.New("missing rules")
+	}
+
+	var rules []Rule
+	for i, raw := range in {
+		rule, err := unmarshalRule(raw)
+		if err != nil {
+			return nil, fmt.Errorf("rule %d: %v", i, err)
+		}
+		rules = append(rules, rule)
+	}
+	return rules, nil
+}
+
+// ruleSpec is the JSON representation of every kind of rule.
+type ruleSpec struct {
+	Type   string            `json:"type"`
+	Levels []string          `json:"levels"`
+	States []string          `json:"states"`
+	Field  string            `json:"field"`
+	Value  *int              `json:"value"`
+	Names  []string          `json:"names"`
+	Rules  []json.RawMessage `json:"rules"`
+	Rule   json.RawMessage   `json:"rule"`
+}
+
+func unmarshalRule(data []byte) (Rule, error) {
+	var spec ruleSpec
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&spec); err != nil {
+		return nil, err
+	}
+
+	switch spec.Type {
+	case "securityLevel":
+		rule := &SecurityLevelRule{}
+		for _, s := range spec.Levels {
+			l, err := ParseSecurityLevel(s)
+			if err != nil {
+				return nil, fmt.Errorf("unknown security level %q", s)
+			}
+			rule.Levels = append(rule.Levels, l)
+		}
+		return rule, nil
+	case "deviceLocked":
+		return &DeviceLockedRule{}, nil
+	case "verifiedBootState":
+		rule := &VerifiedBootStateRule{}
+		for _, s := range spec.States {
+			st, err := ParseVerifiedBootState(s)
+			if err != nil {
+				return nil, fmt.Errorf("unknown verified boot state %q", s)
+			}
+			rule.States = append(rule.States, st)
+		}
+		return rule, nil
+	case "minimum":
+		field, ok := minimumFields[spec.Field]
+		if !ok {
+			return nil, fmt.Errorf("unknown field %q", spec.Field)
+		}
+		if spec.Value == nil {
+			return nil, errors.New("missing value")
+		}
+		if field.patchLevel && !PatchLevel(*spec.Value).Valid() {
+			return nil, fmt.Errorf("invalid patch level %d", *spec.Value)
+		}
+		return &MinimumRule{Field: spec.Field, Value: *spec.Value}, nil
+	case "packageName":
+		return &PackageNameRule{Names: spec.Names}, nil
+	case "all", "any":
+		rules, err := unmarshalRules(spec.Rules)
+		if err != nil {
+			return nil, err
+		}
+		if spec.Type == "all" {
+			return &AllRule{Rules: rules}, nil
+		}
+		return &AnyRule{Rules: rules}, nil
+	case "not":
+		if spec.Rule == nil {
+			return nil, errors.New("missing rule")
+		}
+		rule, err := unmarshalRule(spec.Rule)
+		if err != nil {
+			return nil, err
+		}
+		return &NotRule{Rule: rule}, nil
+	default:
+		return nil, fmt.Errorf("unknown rule type %q", spec.Type)
+	}
+}
+
+func pass(rule Rule, format string, a ...any) RuleResult {
+	return RuleResult{Rule: rule.Name(), Passed: true, Reason: fmt.Sprintf(format, a...)}
+}
+
+func fail(rule Rule, format string, a ...any) RuleResult {
+	return RuleResult{Rule: rule.Name(), Passed: false, Reason: fmt.Sprintf(format, a...)}
+}
+
+// SecurityLevelRule requires both the attestation and the Keymaster security levels to be one
+// of Levels.
+type SecurityLevelRule struct {
+	Levels []SecurityLevel
+}
+
+// Name implements the Rule interface.
+func (r *SecurityLevelRule) Name() string {
+	return fmt.Sprintf("securityLevel in %v", r.Levels)
+}
+
+// Evaluate implements the Rule interface.
+func (r *SecurityLevelRule) Evaluate(keyDesc *KeyDescription) RuleResult {
+	if !slices.Contains(r.Levels, keyDesc.AttestationSecurityLevel) {
+		return fail(r, "AttestationSecurityLevel is %v", keyDesc.AttestationSecurityLevel)
+	}
+	if !slices.Contains(r.Levels, keyDesc.KeymasterSecurityLevel) {
+		return fail(r, "KeymasterSecurityLevel is %v", keyDesc.KeymasterSecurityLevel)
+	}
+	return pass(r, "security level is %v", keyDesc.AttestationSecurityLevel)
+}
+
+// DeviceLockedRule requires the hardware-enforced RootOfTrust to report a locked bootloader.
+type DeviceLockedRule struct{}
+
+// Name implements the Rule interface.
+func (r *DeviceLockedRule) Name() string {
+	return "deviceLocked"
+}
+
+// Evaluate implements the Rule interface.
+func (r *DeviceLockedRule) Evaluate(keyDesc *KeyDescription) RuleResult {
+	rot := keyDesc.TeeEnforced.RootOfTrust
+	if rot == nil {
+		return fail(r, "RootOfTrust is missing")
+	}
+	if !rot.DeviceLocked {
+		return fail(r, "device is unlocked")
+	}
+	return pass(r, "device is locked")
+}
+
+// VerifiedBootStateRule requires the hardware-enforced RootOfTrust to report one of States.
+type VerifiedBootStateRule struct {
+	States []VerifiedBootState
+}
+
+// Name implements the Rule interface.
+func (r *VerifiedBootStateRule) Name() string {
+	return fmt.Sprintf("verifiedBootState in %v", r.States)
+}
+
+// Evaluate implements the Rule interface.
+func (r *VerifiedBootStateRule) Evaluate(keyDesc *KeyDescription) RuleResult {
+	rot := keyDesc.TeeEnforced.RootOfTrust
+	if rot == nil {
+		return fail(r, "RootOfTrust is missing")
+	}
+	if !slices.Contains(r.States, rot.VerifiedBootState) {
+		return fail(r, "VerifiedBootState is %v", rot.VerifiedBootState)
+	}
+	return pass(r, "VerifiedBootState is %v", rot.VerifiedBootState)
+}
+
+// minimumField is a numeric field supported by MinimumRule.
+type minimumField struct {
+	get func(keyDesc *KeyDescription) (int, bool)
+	// patchLevel compares the field as a PatchLevel rather than as an integer.
+	patchLevel bool
+}
+
+// minimumFields lists the numeric fields supported by MinimumRule.
+var minimumFields = map[string]minimumField{
+	"attestationVersion": {get: func(keyDesc *KeyDescription) (int, bool) {
+		return int(keyDesc.AttestationVersion), true
+	}},
+	"keymasterVersion": {get: func(keyDesc *KeyDescription) (int, bool) {
+		return int(keyDesc.KeymasterVersion), true
+	}},
+	"osVersion": {get: func(keyDesc *KeyDescription) (int, bool) {
+		return optionalInt(keyDesc.TeeEnforced.OsVersion)
+	}},
+	"osPatchLevel": {get: func(keyDesc *KeyDescription) (int, bool) {
+		return optionalInt(keyDesc.TeeEnforced.OsPatchLevel)
+	}, patchLevel: true},
+	"vendorPatchLevel": {get: func(keyDesc *KeyDescription) (int, bool) {
+		return optionalInt(keyDesc.TeeEnforced.VendorPatchLevel)
+	}, patchLevel: true},
+	"bootPatchLevel": {get: func(keyDesc *KeyDescription) (int, bool) {
+		return optionalInt(keyDesc.TeeEnforced.BootPatchLevel)
+	}, patchLevel: true},
+}
+
+func optionalInt[T ~int](v *T) (int, bool) {
+	if v == nil {
+		return 0, false
+	}
+	return int(*v), true
+}
+
+// MinimumRule requires a numeric field to be greater than or equal to Value. Field is one of
+// attestationVersion, keymasterVersion, osVersion, osPatchLevel, vendorPatchLevel or
+// bootPatchLevel. Authorization list fields are read from the hardware-enforced list.
+//
+// Patch levels are compared as dates, so a YYYYMM Value applies to YYYYMMDD patch levels too.
+type MinimumRule struct {
+	Field string
+	Value int
+}
+
+// Name implements the Rule interface.
+func (r *MinimumRule) Name() string {
+	return fmt.Sprintf("%s >= %d", r.Field, r.Value)
+}
+
+// Evaluate implements the Rule interface.
+func (r *MinimumRule) Evaluate(keyDesc *KeyDescription) RuleResult {
+	field, ok := minimumFields[r.Field]
+	if !ok {
+		return fail(r, "unknown field %q", r.Field)
+	}
+	v, ok := field.get(keyDesc)
+	if !ok {
+		return fail(r, "%s is missing", r.Field)
+	}
+	if field.patchLevel {
+		if PatchLevel(v).Before(PatchLevel(r.Value)) {
+			return fail(r, "%s is %v", r.Field, PatchLevel(v))
+		}
+		return pass(r, "%s is %v", r.Field, PatchLevel(v))
+	}
+	if v < r.Value {
+		return fail(r, "%s is %d", r.Field, v)
+	}
+	return pass(r, "%s is %d", r.Field, v)
+}
+
+// PackageNameRule requires the AttestationApplicationId to contain one of Names.
+type PackageNameRule struct {
+	Names []string
+}
+
+// Name implements the Rule interface.
+func (r *PackageNameRule) Name() string {
+	return fmt.Sprintf("packageName in %v", r.Names)
+}
+
+// Evaluate implements the Rule interface.
+func (r *PackageNameRule) Evaluate(keyDesc *KeyDescription) RuleResult {
+	appId := keyDesc.SoftwareEnforced.AttestationApplicationId
+	if appId == nil {
+		return fail(r, "AttestationApplicationId is missing")
+	}
+
+	var names []string
+	for _, p := range appId.PackageInfos {
+		if slices.Contains(r.Names, p.PackageName) {
+			return pass(r, "package %s is allowed", p.PackageName)
+		}
+		names = append(names, p.PackageName)
+	}
+	return fail(r, "packages [%s] are not allowed", strings.Join(names, " "))
+}
+
+// AllRule passes if all its rules pass.
+type AllRule struct {
+	Rules []Rule
+}
+
+// Name implements the Rule interface.
+func (r *AllRule) Name() string {
+	return "all"
+}
+
+// Evaluate implements the Rule interface.
+func (r *AllRule) Evaluate(keyDesc *KeyDescription) RuleResult {
+	res := RuleResult{Rule: r.Name(), Passed: true}
+	failed := 0
+	for _, rule := range r.Rules {
+		child := rule.Evaluate(keyDesc)
+		if !child.Passed {
+			res.Passed = false
+			failed++
+		}
+		res.Children = append(res.Children, child)
+	}
+	res.Reason = fmt.Sprintf("%d of %d rules failed", failed, len(r.Rules))
+	return res
+}
+
+// AnyRule passes if at least one of its rules passes.
+type AnyRule struct {
+	Rules []Rule
+}
+
+// Name implements the Rule interface.
+func (r *AnyRule) Name() string {
+	return "any"
+}
+
+// Evaluate implements the Rule interface.
+func (r *AnyRule) Evaluate(keyDesc *KeyDescription) RuleResult {
+	res := RuleResult{Rule: r.Name()}
+	passed := 0
+	for _, rule := range r.Rules {
+		child := rule.Evaluate(keyDesc)
+		if child.Passed {
+			res.Passed = true
+			passed++
+		}
+		res.Children = append(res.Children, child)
+	}
+	res.Reason = fmt.Sprintf("%d of %d rules passed", passed, len(r.Rules))
+	return res
+}
+
+// NotRule passes if its rule fails.
+type NotRule struct {
+	Rule Rule
+}
+
+// Name implements the Rule interface.
+func (r *NotRule) Name() string {
+	return "not " + r.Rule.Name()
+}
+
+// Evaluate implements the Rule interface.
+func (r *NotRule) Evaluate(keyDesc *KeyDescription) RuleResult {
+	child := r.Rule.Evaluate(keyDesc)
+	return RuleResult{
+		Rule:     r.Name(),
+		Passed:   !child.Passed,
+		Reason:   child.Reason,
+		Children: []RuleResult{child},
+	}
+}
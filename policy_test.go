@@ -0,0 +1,209 @@
+package attestation
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+const testPolicy = `{
+  "rules": [
+    {"type": "securityLevel", "levels": ["TrustedEnvironment", "StrongBox"]},
+    {"type": "deviceLocked"},
+    {"type": "verifiedBootState", "states": ["Verified"]},
+    {"type": "minimum", "field": "osPatchLevel", "value": 202301},
+    {"type": "any", "rules": [
+      {"type": "packageName", "names": ["com.example.app"]},
+      {"type": "not", "rule": {"type": "minimum", "field": "attestationVersion", "value": 3}}
+    ]}
+  ]
+}`
+
+func TestParsePolicy(t *testing.T) {
+	tests := []struct {
+		name    string
+		input   string
+		want    *Policy
+		wantErr bool
+	}{
+		{
+			name:    "shouldFailWithUnknownType",
+			input:   `{"rules": [{"type": "unknown"}]}`,
+			wantErr: true,
+		},
+		{
+			name:    "shouldFailWithUnknownMember",
+			input:   `{"rules": [{"type": "deviceLocked", "locked": true}]}`,
+			wantErr: true,
+		},
+		{
+			name:    "shouldFailWithUnknownSecurityLevel",
+			input:   `{"rules": [{"type": "securityLevel", "levels": ["TEE"]}]}`,
+			wantErr: true,
+		},
+		{
+			name:    "shouldFailWithUnknownField",
+			input:   `{"rules": [{"type": "minimum", "field": "keySize", "value": 2048}]}`,
+			wantErr: true,
+		},
+		{
+			name:    "shouldFailWithInvalidPatchLevel",
+			input:   `{"rules": [{"type": "minimum", "field": "bootPatchLevel", "value": 202313}]}`,
+			wantErr: true,
+		},
+		{
+			name:    "shouldFailWithUnknownTopLevelMember",
+			input:   `{"rule": [{"type": "deviceLocked"}]}`,
+			wantErr: true,
+		},
+		{
+			name:    "shouldFailWithoutRules",
+			input:   `{"rules": []}`,
+			wantErr: true,
+		},
+		{
+			name:    "shouldFailWithEmptyAny",
+			input:   `{"rules": [{"type": "any", "rules": []}]}`,
+			wantErr: true,
+		},
+		{
+			name:    "shouldFailWithMissingValue",
+			input:   `{"rules": [{"type": "minimum", "field": "osPatchLevel"}]}`,
+			wantErr: true,
+		},
+		{
+			name:    "shouldFailWithMissingRule",
+			input:   `{"rules": [{"type": "not"}]}`,
+			wantErr: true,
+		},
+		{
+			name:  "shouldSucceedWithRules",
+			input: testPolicy,
+			want: &Policy{Rules: []Rule{
+				&SecurityLevelRule{Levels: []SecurityLevel{TrustedEnvironment, StrongBox}},
+				&DeviceLockedRule{},
+				&VerifiedBootStateRule{States: []VerifiedBootState{Verified}},
+				&MinimumRule{Field: "osPatchLevel", Value: 202301},
+				&AnyRule{Rules: []Rule{
+					&PackageNameRule{Names: []string{"com.example.app"}},
+					&NotRule{Rule: &MinimumRule{Field: "attestationVersion", Value: 3}},
+				}},
+			}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParsePolicy(strings.NewReader(tt.input))
+			if (err != nil) != tt.wantErr {
+				t.Errorf("ParsePolicy() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ParsePolicy() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPolicy_Evaluate(t *testing.T) {
+	policy, err := ParsePolicy(strings.NewReader(testPolicy))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	patchLevel := PatchLevel(202305)
+	keyDesc := &KeyDescription{
+		AttestationVersion:       KAKeyMintVersion2,
+		AttestationSecurityLevel: TrustedEnvironment,
+		KeymasterVersion:         KeyMintVersion2,
+		KeymasterSecurityLevel:   TrustedEnvironment,
+		SoftwareEnforced: AuthorizationList{
+			AttestationApplicationId: &AttestationApplicationId{
+				PackageInfos: []*AttestationPackageInfo{{PackageName: "com.example.app", Version: 1}},
+			},
+		},
+		TeeEnforced: AuthorizationList{
+			RootOfTrust:  &RootOfTrust{DeviceLocked: true, VerifiedBootState: Verified},
+			OsPatchLevel: &patchLevel,
+		},
+	}
+
+	tests := []struct {
+		name       string
+		modify     func(kd *KeyDescription)
+		want       bool
+		wantFailed []string
+	}{
+		{
+			name:   "shouldPass",
+			modify: func(kd *KeyDescription) {},
+			want:   true,
+		},
+		{
+			name: "shouldFailWhenSoftware",
+			modify: func(kd *KeyDescription) {
+				kd.KeymasterSecurityLevel = Software
+			},
+			wantFailed: []string{"securityLevel in [TrustedEnvironment StrongBox]"},
+		},
+		{
+			name: "shouldFailWhenUnlockedAndUnverified",
+			modify: func(kd *KeyDescription) {
+				kd.TeeEnforced.RootOfTrust = &RootOfTrust{VerifiedBootState: Unverified}
+			},
+			wantFailed: []string{"deviceLocked", "verifiedBootState in [Verified]"},
+		},
+		{
+			name: "shouldFailWhenPatchLevelIsOld",
+			modify: func(kd *KeyDescription) {
+				old := PatchLevel(202212)
+				kd.TeeEnforced.OsPatchLevel = &old
+			},
+			wantFailed: []string{"osPatchLevel >= 202301"},
+		},
+		{
+			name: "shouldPassWhenPatchLevelHasDay",
+			modify: func(kd *KeyDescription) {
+				day := PatchLevel(20230105)
+				kd.TeeEnforced.OsPatchLevel = &day
+			},
+			want: true,
+		},
+		{
+			name: "shouldPassWhenLegacyAttestation",
+			modify: func(kd *KeyDescription) {
+				kd.AttestationVersion = KAKeymasterVersion2
+				kd.SoftwareEnforced.AttestationApplicationId = nil
+			},
+			want: true,
+		},
+		{
+			name: "shouldFailWhenPackageIsUnknown",
+			modify: func(kd *KeyDescription) {
+				kd.SoftwareEnforced.AttestationApplicationId = &AttestationApplicationId{
+					PackageInfos: []*AttestationPackageInfo{{PackageName: "com.example.other"}},
+				}
+			},
+			wantFailed: []string{"any"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			kd := *keyDesc
+			tt.modify(&kd)
+
+			got := policy.Evaluate(&kd)
+			if got.Passed != tt.want {
+				t.Errorf("Evaluate() = %+v, want %v", got, tt.want)
+			}
+
+			var failed []string
+			for _, res := range got.Failed() {
+				failed = append(failed, res.Rule)
+			}
+			if !reflect.DeepEqual(failed, tt.wantFailed) {
+				t.Errorf("Evaluate() failed = %q, want %q", failed, tt.wantFailed)
+			}
+		})
+	}
+}
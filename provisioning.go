@@ -0,0 +1,87 @@
+package attestation
+
+import (
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"errors"
+	"fmt"
+)
+
+// OIDProvisioningInfoExtension is the provisioning information extension carried by
+// intermediate certificates issued through Remote Key Provisioning.
+var OIDProvisioningInfoExtension = asn1.ObjectIdentifier{1, 3, 6, 1, 4, 1, 11129, 2, 1, 30}
+
+// Provisioning information map keys.
+const (
+	provisioningInfoCertsIssued  = 1
+	provisioningInfoManufacturer = 3
+)
+
+// ProvisioningInfo reflects the provisioning information extension, a CBOR map.
+//
+//	ProvisioningInfo = {
+//		1 : int,   ; certs_issued
+//		3 : tstr,  ; manufacturer
+//	}
+type ProvisioningInfo struct {
+	Raw []byte
+	// CertsIssued is the number of certificates issued to the device in the last 30 days.
+	CertsIssued *int64
+	// Manufacturer is the manufacturer of the device, as reported by ro.product.manufacturer.
+	Manufacturer string
+}
+
+// ParseProvisioningInfo parses a ProvisioningInfo from the given CBOR data. Unknown map keys
+// are ignored.
+func ParseProvisioningInfo(data []byte) (*ProvisioningInfo, error) {
+	info := &ProvisioningInfo{Raw: data}
+
+	d := &cborDecoder{data: data}
+	n, err := d.readMapHeader()
+	if err != nil {
+		return nil, fmt.Errorf("attestation: ProvisioningInfo: %v", err)
+	}
+
+	for i := 0; i < n; i++ {
+		key, err := d.readInt()
+		if err != nil {
+			return nil, fmt.Errorf("attestation: ProvisioningInfo: key: %v", err)
+		}
+
+		switch key {
+		case provisioningInfoCertsIssued:
+			v, err := d.readInt()
+			if err != nil {
+				return nil, fmt.Errorf("attestation: ProvisioningInfo: certs_issued: %v", err)
+			}
+			info.CertsIssued = &v
+		case provisioningInfoManufacturer:
+			v, err := d.readString(cborTextString)
+			if err != nil {
+				return nil, fmt.Errorf("attestation: ProvisioningInfo: manufacturer: %v", err)
+			}
+			info.Manufacturer = string(v)
+		default:
+			if err := d.skip(0); err != nil {
+				return nil, fmt.Errorf("attestation: ProvisioningInfo: key %d: %v", key, err)
+			}
+		}
+	}
+
+	if len(d.data) != 0 {
+		return nil, errors.New("attestation: trailing data after ProvisioningInfo")
+	}
+
+	return info, nil
+}
+
+// GetProvisioningInfoExtension returns the provisioning information extension.
+func GetProvisioningInfoExtension(crt *x509.Certificate) *pkix.Extension {
+	for _, ext := range crt.Extensions {
+		if ext.Id.Equal(OIDProvisioningInfoExtension) {
+			return &ext
+		}
+	}
+	return nil
+}
@@ -0,0 +1,90 @@
+package attestation
+
+import (
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"math/big"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestParseProvisioningInfo(t *testing.T) {
+	raw := []byte{
+		0xa2,       // map(2)
+		0x01, 0x05, // certs_issued: 5
+		0x03, 0x66, 'G', 'o', 'o', 'g', 'l', 'e', // manufacturer: "Google"
+	}
+	rawUnknown := []byte{
+		0xa3,                   // map(3)
+		0x02, 0x82, 0xf5, 0x40, // 2: [true, h'']
+		0x20, 0x19, 0x01, 0x00, // -1: 256
+		0x01, 0x18, 0x64, // certs_issued: 100
+	}
+
+	certsIssued := int64(5)
+	certsIssuedUnknown := int64(100)
+
+	tests := []struct {
+		name    string
+		data    []byte
+		want    *ProvisioningInfo
+		wantErr bool
+	}{
+		{name: "shouldFailWhenNil", data: nil, wantErr: true},
+		{name: "shouldFailWhenNotAMap", data: []byte{0x80}, wantErr: true},
+		{name: "shouldFailWhenIndefiniteLength", data: []byte{0xbf, 0x01, 0x05, 0xff}, wantErr: true},
+		{name: "shouldFailWhenTruncated", data: raw[:len(raw)-1], wantErr: true},
+		{name: "shouldFailWhenTrailingData", data: append(append([]byte{}, raw...), 0x00), wantErr: true},
+		{name: "shouldFailWhenManufacturerIsNotText", data: []byte{0xa1, 0x03, 0x41, 'G'}, wantErr: true},
+		{
+			name: "shouldSucceedWhenEmpty",
+			data: []byte{0xa0},
+			want: &ProvisioningInfo{Raw: []byte{0xa0}},
+		},
+		{
+			name: "shouldSucceedWithValues",
+			data: raw,
+			want: &ProvisioningInfo{Raw: raw, CertsIssued: &certsIssued, Manufacturer: "Google"},
+		},
+		{
+			name: "shouldSucceedWithUnknownKeys",
+			data: rawUnknown,
+			want: &ProvisioningInfo{Raw: rawUnknown, CertsIssued: &certsIssuedUnknown},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseProvisioningInfo(tt.data)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("ParseProvisioningInfo() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ParseProvisioningInfo() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestGetProvisioningInfoExtension(t *testing.T) {
+	value := []byte{0xa1, 0x01, 0x01}
+	crt := newTestCert(t, &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "Test Intermediate"},
+		NotBefore:    time.Unix(0, 0),
+		NotAfter:     time.Unix(0, 0).AddDate(1, 0, 0),
+		ExtraExtensions: []pkix.Extension{
+			{Id: OIDProvisioningInfoExtension, Value: value},
+		},
+	}, nil).crt
+
+	ext := GetProvisioningInfoExtension(crt)
+	if ext == nil || !reflect.DeepEqual(ext.Value, value) {
+		t.Errorf("GetProvisioningInfoExtension() = %+v, want value %x", ext, value)
+	}
+
+	if ext := GetKeyExtension(crt); ext != nil {
+		t.Errorf("GetKeyExtension() = %+v, want nil", ext)
+	}
+}
@@ -0,0 +1,133 @@
+package attestation
+
+import (
+	"context"
+	"crypto/x509"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+	"os"
+	"strings"
+)
+
+// RevocationListURL is the location of the attestation certificate revocation status list
+// published by Google.
+const RevocationListURL = "https://android.googleapis.com/attestation/status"
+
+// ErrRevoked is returned when a certificate of the chain is revoked or suspended.
+var ErrRevoked = errors.New("attestation: certificate revoked")
+
+// RevocationStatus is the status of a revoked certificate.
+type RevocationStatus string
+
+const (
+	StatusRevoked   RevocationStatus = "REVOKED"
+	StatusSuspended RevocationStatus = "SUSPENDED"
+)
+
+// RevocationReason is the reason why a certificate was revoked.
+type RevocationReason string
+
+const (
+	ReasonUnspecified   RevocationReason = "UNSPECIFIED"
+	ReasonKeyCompromise RevocationReason = "KEY_COMPROMISE"
+	ReasonCACompromise  RevocationReason = "CA_COMPROMISE"
+	ReasonSuperseded    RevocationReason = "SUPERSEDED"
+	ReasonSoftwareFlaw  RevocationReason = "SOFTWARE_FLAW"
+)
+
+// RevocationEntry reflects the status of a single certificate in the revocation status list.
+type RevocationEntry struct {
+	Status  RevocationStatus `json:"status"`
+	Expires string           `json:"expires,omitempty"`
+	Reason  RevocationReason `json:"reason,omitempty"`
+	Comment string           `json:"comment,omitempty"`
+}
+
+// RevocationList reflects the attestation certificate revocation status list.
+//
+// Entries are keyed by certificate serial number, encoded in lowercase hexadecimal.
+type RevocationList struct {
+	Entries map[string]*RevocationEntry `json:"entries"`
+}
+
+// ParseRevocationList parses a revocation status list in the JSON format published by Google.
+func ParseRevocationList(r io.Reader) (*RevocationList, error) {
+	var in RevocationList
+	if err := json.NewDecoder(r).Decode(&in); err != nil {
+		return nil, fmt.Errorf("attestation: %v", err)
+	}
+
+	out := &RevocationList{Entries: make(map[string]*RevocationEntry, len(in.Entries))}
+	for serial, entry := range in.Entries {
+		if entry == nil {
+			return nil, fmt.Errorf("attestation: empty revocation entry for serial %q", serial)
+		}
+		out.Entries[normalizeSerial(serial)] = entry
+	}
+
+	return out, nil
+}
+
+// normalizeSerial returns the canonical hexadecimal representation of a serial number.
+func normalizeSerial(serial string) string {
+	if n, ok := new(big.Int).SetString(serial, 16); ok {
+		return n.Text(16)
+	}
+	return strings.ToLower(serial)
+}
+
+// Lookup returns the revocation entry of the certificate if present.
+func (l *RevocationList) Lookup(crt *x509.Certificate) (*RevocationEntry, bool) {
+	if l == nil || crt.SerialNumber == nil {
+		return nil, false
+	}
+	entry, ok := l.Entries[crt.SerialNumber.Text(16)]
+	return entry, ok
+}
+
+// check returns an error if any certificate of the chain is revoked or suspended.
+func (l *RevocationList) check(chain []*x509.Certificate) error {
+	for i, crt := range chain {
+		if entry, ok := l.Lookup(crt); ok {
+			return fmt.Errorf("%w: certificate %d (serial %s) is %s: %s", ErrRevoked, i, crt.SerialNumber.Text(16), entry.Status, entry.Reason)
+		}
+	}
+	return nil
+}
+
+// RevocationFetcher is the interface implemented by sources of revocation status lists.
+type RevocationFetcher interface {
+	FetchRevocationList(ctx context.Context) (*RevocationList, error)
+}
+
+// StaticRevocationFetcher is a RevocationFetcher returning an in-memory list.
+type StaticRevocationFetcher struct {
+	List *RevocationList
+}
+
+// FetchRevocationList returns the in-memory list.
+func (f *StaticRevocationFetcher) FetchRevocationList(ctx context.Context) (*RevocationList, error) {
+	if f.List == nil {
+		return nil, errors.New("attestation: no revocation list")
+	}
+	return f.List, nil
+}
+
+// FileRevocationFetcher is a RevocationFetcher reading a list from the file system.
+type FileRevocationFetcher struct {
+	Path string
+}
+
+// FetchRevocationList reads and parses the list stored at Path.
+func (f *FileRevocationFetcher) FetchRevocationList(ctx context.Context) (*RevocationList, error) {
+	file, err := os.Open(f.Path)
+	if err != nil {
+		return nil, fmt.Errorf("attestation: %v", err)
+	}
+	defer file.Close()
+
+	return ParseRevocationList(file)
+}
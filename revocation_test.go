@@ -0,0 +1,166 @@
+package attestation
+
+import (
+	"context"
+	"crypto/x509"
+	"errors"
+	"math/big"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+const testRevocationList = `{
+  "entries": {
+    "2c8cdddfd5e03bfc": {
+      "status": "REVOKED",
+      "expires": "2020-11-13",
+      "reason": "KEY_COMPROMISE",
+      "comment": "Key stored on unsecure system"
+    },
+    "00C8966FCB2FBB0D7A": {
+      "status": "SUSPENDED",
+      "reason": "SOFTWARE_FLAW"
+    },
+    "2": {
+      "status": "REVOKED",
+      "reason": "CA_COMPROMISE"
+    }
+  }
+}`
+
+func TestParseRevocationList(t *testing.T) {
+	tests := []struct {
+		name    string
+		input   string
+		want    *RevocationList
+		wantErr bool
+	}{
+		{
+			name:    "shouldFailWhenInvalid",
+			input:   `{"entries": [`,
+			wantErr: true,
+		},
+		{
+			name:    "shouldFailWhenEntryIsNull",
+			input:   `{"entries": {"01": null}}`,
+			wantErr: true,
+		},
+		{
+			name:  "shouldSucceedWhenEmpty",
+			input: `{}`,
+			want:  &RevocationList{Entries: map[string]*RevocationEntry{}},
+		},
+		{
+			name:  "shouldSucceedWithEntries",
+			input: testRevocationList,
+			want: &RevocationList{Entries: map[string]*RevocationEntry{
+				"2c8cdddfd5e03bfc": {
+					Status:  StatusRevoked,
+					Expires: "2020-11-13",
+					Reason:  ReasonKeyCompromise,
+					Comment: "Key stored on unsecure system",
+				},
+				"c8966fcb2fbb0d7a": {
+					Status: StatusSuspended,
+					Reason: ReasonSoftwareFlaw,
+				},
+				"2": {
+					Status: StatusRevoked,
+					Reason: ReasonCACompromise,
+				},
+			}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseRevocationList(strings.NewReader(tt.input))
+			if (err != nil) != tt.wantErr {
+				t.Errorf("ParseRevocationList() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ParseRevocationList() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRevocationList_Lookup(t *testing.T) {
+	list, err := ParseRevocationList(strings.NewReader(testRevocationList))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	serial, _ := new(big.Int).SetString("c8966fcb2fbb0d7a", 16)
+	if entry, ok := list.Lookup(&x509.Certificate{SerialNumber: serial}); !ok || entry.Status != StatusSuspended {
+		t.Errorf("Lookup() = %+v, %v, want %s", entry, ok, StatusSuspended)
+	}
+
+	if entry, ok := list.Lookup(&x509.Certificate{SerialNumber: big.NewInt(1)}); ok {
+		t.Errorf("Lookup() = %+v, %v, want none", entry, ok)
+	}
+}
+
+func TestFileRevocationFetcher(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "status.json")
+	if err := os.WriteFile(path, []byte(testRevocationList), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := (&FileRevocationFetcher{Path: path}).FetchRevocationList(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got.Entries) != 3 {
+		t.Errorf("FetchRevocationList() = %d entries, want 3", len(got.Entries))
+	}
+
+	if _, err := (&FileRevocationFetcher{Path: path + ".missing"}).FetchRevocationList(context.Background()); err == nil {
+		t.Error("FetchRevocationList() error = nil, want error")
+	}
+}
+
+func TestVerifyRevocation(t *testing.T) {
+	now := time.Date(2024, time.January, 1, 0, 0, 0, 0, time.UTC)
+	chain := newTestChain(t, &KeyDescription{}, now.AddDate(1, 0, 0))
+
+	// The test chain intermediate has serial number 2.
+	list, err := (&StaticRevocationFetcher{List: mustParseRevocationList(t, testRevocationList)}).FetchRevocationList(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	opts := VerifyOptions{Roots: []*x509.Certificate{chain[2].crt}, CurrentTime: now, Revocations: list}
+	if _, err := Verify(certificates(chain), opts); !errors.Is(err, ErrRevoked) {
+		t.Errorf("Verify() error = %v, want %v", err, ErrRevoked)
+	}
+
+	opts.Revocations = &RevocationList{}
+	if _, err := Verify(certificates(chain), opts); err != nil {
+		t.Errorf("Verify() error = %v, want nil", err)
+	}
+
+	opts.RevocationFetcher = &StaticRevocationFetcher{List: list}
+	if _, err := VerifyContext(context.Background(), certificates(chain), opts); !errors.Is(err, ErrRevoked) {
+		t.Errorf("VerifyContext() error = %v, want %v", err, ErrRevoked)
+	}
+
+	opts.RevocationFetcher = &StaticRevocationFetcher{}
+	if _, err := Verify(certificates(chain), opts); err == nil {
+		t.Error("Verify() error = nil, want the fetcher error")
+	}
+}
+
+func mustParseRevocationList(t *testing.T, s string) *RevocationList {
+	t.Helper()
+
+	list, err := ParseRevocationList(strings.NewReader(s))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return list
+}
@@ -0,0 +1,58 @@
+package attestation
+
+import (
+	"bytes"
+	"crypto/x509"
+	"encoding/pem"
+	"sync"
+)
+
+// googleRootPublicKeys holds the public keys of the Google hardware attestation roots.
+//
+// See https://developer.android.com/privacy-and-security/security-key-attestation#root_certificate.
+var googleRootPublicKeys = []string{
+	// RSA 4096 root, used by most devices since Android 7.0.
+	`-----BEGIN PUBLIC KEY-----
+MIICIjANBgkqhkiG9w0BAQEFAAOCAg8AMIICCgKCAgEAr7bHgiuxpwHsK7Qui8xU
+FmOr75gvMsd/dTEDDJdSSxtf6An7xyqpRR90PL2abxM1dEqlXnf2tqw1Ne4Xwl5j
+lRfdnJLmN0pTy/4lj4/7tv0Sk3iiKkypnEUtR6WfMgH0QZfKHM1+di+y9TFRtv6y
+//0rb+T+W8a9nsNL/ggjnar86461qO0rOs2cXjp3kOG1FEJ5MVmFmBGtnrKpa73X
+pXyTqRxB/M0n1n/W9nGqC4FSYa04T6N5RIZGBN2z2MT5IKGbFlbC8UrW0DxW7AYI
+mQQcHtGl/m00QLVWutHQoVJYnFPlXTcHYvASLu+RhhsbDmxMgJJ0mcDpvsC4PjvB
++TxywElgS70vE0XmLD+OJtvsBslHZvPBKCOdT0MS+tgSOIfga+z1Z1g7+DVagf7q
+uvmag8jfPioyKvxnK/EgsTUVi2ghzq8wm27ud/mIM7AY2qEORR8Go3TVB4HzWQgp
+Zrt3i5MIlCaY504LzSRiigHCzAPlHws+W0rB5N+er5/2pJKnfBSDiCiFAVtCLOZ7
+gLiMm0jhO2B6tUXHI/+MRPjy02i59lINMRRev56GKtcd9qO/0kUJWdZTdA2XoS82
+ixPvZtXQpUpuL12ab+9EaDK8Z4RHJYYfCT3Q5vNAXaiWQ+8PTWm2QgBR/bkwSWc+
+NpUFgNPN9PvQi8WEg5UmAGMCAwEAAQ==
+-----END PUBLIC KEY-----`,
+	// TODO: add the ECDSA P-384 root once its public key is vendored from the
+	// published root list. Until then it can be trusted through VerifyOptions.Roots.
+}
+
+// googleRoots returns the DER encoded SubjectPublicKeyInfo of the Google hardware attestation roots.
+var googleRoots = sync.OnceValue(func() [][]byte {
+	var roots [][]byte
+	for _, key := range googleRootPublicKeys {
+		block, _ := pem.Decode([]byte(key))
+		if block == nil {
+			panic("attestation: invalid root public key")
+		}
+		if _, err := x509.ParsePKIXPublicKey(block.Bytes); err != nil {
+			panic("attestation: invalid root public key: " + err.Error())
+		}
+		roots = append(roots, block.Bytes)
+	}
+	return roots
+})
+
+// IsGoogleRoot reports whether the certificate carries one of the Google hardware attestation
+// root public keys.
+func IsGoogleRoot(crt *x509.Certificate) bool {
+	for _, spki := range googleRoots() {
+		if bytes.Equal(crt.RawSubjectPublicKeyInfo, spki) {
+			return true
+		}
+	}
+	return false
+}
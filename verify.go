@@ -0,0 +1,223 @@
+package attestation
+
+import (
+	"bytes"
+	"context"
+	"crypto/ecdh"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"time"
+)
+
+var (
+	// ErrEmptyChain is returned when the certificate chain is empty.
+	ErrEmptyChain = errors.New("attestation: empty certificate chain")
+	// ErrInvalidChain is returned when a certificate is not issued by the next one in the chain.
+	ErrInvalidChain = errors.New("attestation: invalid certificate chain")
+	// ErrCertificateExpired is returned when a certificate is not valid at the verification time.
+	ErrCertificateExpired = errors.New("attestation: certificate expired or not yet valid")
+	// ErrUntrustedRoot is returned when the chain does not terminate in a trusted root.
+	ErrUntrustedRoot = errors.New("attestation: certificate chain does not terminate in a trusted root")
+	// ErrMissingExtension is returned when the leaf certificate lacks the key attestation extension.
+	ErrMissingExtension = errors.New("attestation: key attestation extension not found")
+	// ErrKeyMismatch is returned when the leaf public key does not match the key properties of
+	// the KeyDescription.
+	ErrKeyMismatch = errors.New("attestation: public key does not match the key description")
+)
+
+// VerifyOptions contains parameters for Verify.
+type VerifyOptions struct {
+	// Roots is an optional set of additional trust anchors. The Google hardware attestation roots
+	// are always trusted.
+	Roots []*x509.Certificate
+	// CurrentTime is used to check the validity of all certificates in the chain. If zero, the
+	// current time is used.
+	CurrentTime time.Time
+	// Revocations is an optional revocation status list. Chains containing a revoked or suspended
+	// certificate are rejected.
+	Revocations *RevocationList
+	// RevocationFetcher is an optional source of revocation status list, fetched on each
+	// verification. Chains are checked against the fetched list in addition to Revocations.
+	RevocationFetcher RevocationFetcher
+	// CheckKeyProperties checks the leaf public key against the KeyDescription with
+	// CheckKeyProperties.
+	CheckKeyProperties bool
+}
+
+// Verify verifies an attestation certificate chain and returns the KeyDescription of its leaf.
+//
+// The chain is expected in the order returned by Android's KeyStore, the leaf first and the root
+// last. Each certificate must be valid at opts.CurrentTime and signed by the next one, which must
+// be a CA. Only the leaf may carry the key attestation extension. The last certificate must either
+// carry a Google hardware attestation root key, match one of opts.Roots, or be signed by one of
+// opts.Roots.
+func Verify(chain []*x509.Certificate, opts VerifyOptions) (*KeyDescription, error) {
+	return VerifyContext(context.Background(), chain, opts)
+}
+
+// VerifyContext is like Verify but uses ctx to fetch the revocation status list of
+// opts.RevocationFetcher.
+func VerifyContext(ctx context.Context, chain []*x509.Certificate, opts VerifyOptions) (*KeyDescription, error) {
+	if len(chain) == 0 {
+		return nil, ErrEmptyChain
+	}
+
+	now := opts.CurrentTime
+	if now.IsZero() {
+		now = time.Now()
+	}
+
+	for i, crt := range chain {
+		// Only the leaf is attested. An attested key must not be able to issue a certificate
+		// carrying its own KeyDescription.
+		if i > 0 && GetKeyExtension(crt) != nil {
+			return nil, fmt.Errorf("%w: certificate %d carries a key attestation extension", ErrInvalidChain, i)
+		}
+
+		if now.Before(crt.NotBefore) || now.After(crt.NotAfter) {
+			return nil, fmt.Errorf("%w: certificate %d (%s)", ErrCertificateExpired, i, crt.Subject)
+		}
+
+		if i+1 < len(chain) {
+			if err := checkIssuedBy(crt, chain[i+1]); err != nil {
+				return nil, fmt.Errorf("%w: certificate %d: %v", ErrInvalidChain, i, err)
+			}
+		}
+	}
+
+	if err := checkTrusted(chain[len(chain)-1], opts.Roots); err != nil {
+		return nil, err
+	}
+
+	if opts.Revocations != nil {
+		if err := opts.Revocations.check(chain); err != nil {
+			return nil, err
+		}
+	}
+
+	if opts.RevocationFetcher != nil {
+		list, err := opts.RevocationFetcher.FetchRevocationList(ctx)
+		if err != nil {
+			return nil, err
+		}
+		if err := list.check(chain); err != nil {
+			return nil, err
+		}
+	}
+
+	leaf := chain[0]
+	ext := GetKeyExtension(leaf)
+	if ext == nil {
+		return nil, ErrMissingExtension
+	}
+
+	keyDesc, err := ParseExtension(ext.Value)
+	if err != nil {
+		return nil, err
+	}
+
+	if opts.CheckKeyProperties {
+		if err := CheckKeyProperties(leaf, keyDesc); err != nil {
+			return nil, err
+		}
+	}
+
+	return keyDesc, nil
+}
+
+// CheckKeyProperties checks that the public key of crt matches the Algorithm, KeySize, EcCurve
+// and RsaPublicExponent of keyDesc. Each property is read from the hardware-enforced list, or
+// from the software-enforced list when not hardware-enforced. Missing properties are not checked.
+func CheckKeyProperties(crt *x509.Certificate, keyDesc *KeyDescription) error {
+	algorithm := enforced(keyDesc, func(al *AuthorizationList) *Algorithm { return al.Algorithm })
+	keySize := enforced(keyDesc, func(al *AuthorizationList) *int { return al.KeySize })
+	curve := enforced(keyDesc, func(al *AuthorizationList) *EcCurve { return al.EcCurve })
+	exponent := enforced(keyDesc, func(al *AuthorizationList) *int64 { return al.RsaPublicExponent })
+
+	var (
+		wantAlgorithm Algorithm
+		wantKeySize   int
+		wantCurve     *EcCurve
+		wantExponent  *int64
+	)
+
+	switch pub := crt.PublicKey.(type) {
+	case *rsa.PublicKey:
+		e := int64(pub.E)
+		wantAlgorithm, wantKeySize, wantExponent = AlgoRSA, pub.N.BitLen(), &e
+	case *ecdsa.PublicKey:
+		c, ok := EcCurveOf(pub.Curve)
+		if !ok {
+			return fmt.Errorf("%w: unsupported curve %s", ErrKeyMismatch, pub.Curve.Params().Name)
+		}
+		wantAlgorithm, wantKeySize, wantCurve = AlgoEC, pub.Curve.Params().BitSize, &c
+	case ed25519.PublicKey, *ecdh.PublicKey:
+		c := Curve25519
+		wantAlgorithm, wantKeySize, wantCurve = AlgoEC, 256, &c
+	default:
+		return fmt.Errorf("%w: unsupported public key type %T", ErrKeyMismatch, pub)
+	}
+
+	if algorithm != nil && *algorithm != wantAlgorithm {
+		return fmt.Errorf("%w: Algorithm is %v, public key is %v", ErrKeyMismatch, *algorithm, wantAlgorithm)
+	}
+	if keySize != nil && *keySize != wantKeySize {
+		return fmt.Errorf("%w: KeySize is %d, public key is %d bits", ErrKeyMismatch, *keySize, wantKeySize)
+	}
+	if curve != nil && wantCurve != nil && *curve != *wantCurve {
+		return fmt.Errorf("%w: EcCurve is %v, public key is %v", ErrKeyMismatch, *curve, *wantCurve)
+	}
+	if exponent != nil && wantExponent != nil && *exponent != *wantExponent {
+		return fmt.Errorf("%w: RsaPublicExponent is %d, public key is %d", ErrKeyMismatch, *exponent, *wantExponent)
+	}
+
+	return nil
+}
+
+// enforced returns the value of a property in the hardware-enforced list of keyDesc, or in the
+// software-enforced list when not hardware-enforced.
+func enforced[T any](keyDesc *KeyDescription, get func(al *AuthorizationList) *T) *T {
+	if v := get(&keyDesc.TeeEnforced); v != nil {
+		return v
+	}
+	return get(&keyDesc.SoftwareEnforced)
+}
+
+// checkIssuedBy checks that crt is signed by parent. Attestation certificates do not always follow
+// the X.509 profile for CA certificates, so parent only needs to be a CA according to either its
+// basic constraints or its key usage.
+func checkIssuedBy(crt, parent *x509.Certificate) error {
+	if !bytes.Equal(crt.RawIssuer, parent.RawSubject) {
+		return errors.New("issuer does not match parent subject")
+	}
+	if !parent.IsCA && parent.KeyUsage&x509.KeyUsageCertSign == 0 {
+		return errors.New("issuer is not allowed to sign certificates")
+	}
+	return parent.CheckSignature(crt.SignatureAlgorithm, crt.RawTBSCertificate, crt.Signature)
+}
+
+// checkTrusted checks that the last certificate of a chain is a trust anchor or is issued by one.
+// Trust anchors are identified by their public key.
+func checkTrusted(crt *x509.Certificate, roots []*x509.Certificate) error {
+	if IsGoogleRoot(crt) {
+		return nil
+	}
+
+	for _, root := range roots {
+		if bytes.Equal(crt.RawSubjectPublicKeyInfo, root.RawSubjectPublicKeyInfo) {
+			return nil
+		}
+	}
+
+	for _, root := range roots {
+		if checkIssuedBy(crt, root) == nil {
+			return nil
+		}
+	}
+
+	return ErrUntrustedRoot
+}
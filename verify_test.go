@@ -0,0 +1,386 @@
+package attestation
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"errors"
+	"math/big"
+	"reflect"
+	"testing"
+	"time"
+)
+
+type testCert struct {
+	crt *x509.Certificate
+	key crypto.Signer
+}
+
+func newTestCert(t *testing.T, template *x509.Certificate, parent *testCert) *testCert {
+	t.Helper()
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	parentCrt, parentKey := template, crypto.Signer(key)
+	if parent != nil {
+		parentCrt, parentKey = parent.crt, parent.key
+	}
+
+	derBytes, err := x509.CreateCertificate(rand.Reader, template, parentCrt, key.Public(), parentKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	crt, err := x509.ParseCertificate(derBytes)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return &testCert{crt: crt, key: key}
+}
+
+func newTestChain(t *testing.T, keyDesc *KeyDescription, notAfter time.Time) []*testCert {
+	t.Helper()
+
+	root := newTestCert(t, &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "Test Root"},
+		NotBefore:             time.Unix(0, 0),
+		NotAfter:              notAfter,
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+		KeyUsage:              x509.KeyUsageCertSign,
+	}, nil)
+
+	intermediate := newTestCert(t, &x509.Certificate{
+		SerialNumber:          big.NewInt(2),
+		Subject:               pkix.Name{CommonName: "Test Intermediate"},
+		NotBefore:             time.Unix(0, 0),
+		NotAfter:              notAfter,
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+		KeyUsage:              x509.KeyUsageCertSign,
+	}, root)
+
+	leafTemplate := &x509.Certificate{
+		SerialNumber: big.NewInt(3),
+		Subject:      pkix.Name{CommonName: "Android Keystore Key"},
+		NotBefore:    time.Unix(0, 0),
+		NotAfter:     notAfter,
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+	}
+	if keyDesc != nil {
+		ext, err := CreateExtension(keyDesc)
+		if err != nil {
+			t.Fatal(err)
+		}
+		leafTemplate.ExtraExtensions = []pkix.Extension{*ext}
+	}
+	leaf := newTestCert(t, leafTemplate, intermediate)
+
+	return []*testCert{leaf, intermediate, root}
+}
+
+func certificates(chain []*testCert) []*x509.Certificate {
+	var crts []*x509.Certificate
+	for _, c := range chain {
+		crts = append(crts, c.crt)
+	}
+	return crts
+}
+
+func TestVerify(t *testing.T) {
+	keyDesc := &KeyDescription{
+		AttestationVersion:       KAKeyMintVersion2,
+		AttestationSecurityLevel: TrustedEnvironment,
+		KeymasterVersion:         KeyMintVersion2,
+		KeymasterSecurityLevel:   TrustedEnvironment,
+		AttestationChallenge:     []byte("challenge"),
+		UniqueId:                 []byte{},
+	}
+
+	now := time.Date(2024, time.January, 1, 0, 0, 0, 0, time.UTC)
+	valid := newTestChain(t, keyDesc, now.AddDate(10, 0, 0))
+	expired := newTestChain(t, keyDesc, now.AddDate(-1, 0, 0))
+	noExtension := newTestChain(t, nil, now.AddDate(10, 0, 0))
+	other := newTestChain(t, keyDesc, now.AddDate(10, 0, 0))
+
+	// attestedCA is a CA carrying a key attestation extension, which issues another attestation.
+	attestedCA := newTestCert(t, &x509.Certificate{
+		SerialNumber:          big.NewInt(4),
+		Subject:               pkix.Name{CommonName: "Attested CA"},
+		NotBefore:             time.Unix(0, 0),
+		NotAfter:              now.AddDate(10, 0, 0),
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+		KeyUsage:              x509.KeyUsageCertSign,
+		ExtraExtensions:       []pkix.Extension{*GetKeyExtension(valid[0].crt)},
+	}, valid[1])
+	attestedLeaf := newTestCert(t, &x509.Certificate{
+		SerialNumber:    big.NewInt(5),
+		Subject:         pkix.Name{CommonName: "Android Keystore Key"},
+		NotBefore:       time.Unix(0, 0),
+		NotAfter:        now.AddDate(10, 0, 0),
+		ExtraExtensions: []pkix.Extension{*GetKeyExtension(valid[0].crt)},
+	}, attestedCA)
+	// forged is issued by the non-CA leaf of valid.
+	forged := newTestCert(t, &x509.Certificate{
+		SerialNumber: big.NewInt(6),
+		Subject:      pkix.Name{CommonName: "Android Keystore Key"},
+		NotBefore:    time.Unix(0, 0),
+		NotAfter:     now.AddDate(10, 0, 0),
+	}, valid[0])
+
+	type args struct {
+		chain []*x509.Certificate
+		opts  VerifyOptions
+	}
+	tests := []struct {
+		name    string
+		args    args
+		want    *KeyDescription
+		wantErr error
+	}{
+		{
+			name:    "shouldFailWhenEmpty",
+			args:    args{},
+			wantErr: ErrEmptyChain,
+		},
+		{
+			name:    "shouldFailWhenRootIsUntrusted",
+			args:    args{chain: certificates(valid), opts: VerifyOptions{CurrentTime: now}},
+			wantErr: ErrUntrustedRoot,
+		},
+		{
+			name: "shouldFailWhenRootIsOther",
+			args: args{chain: certificates(valid), opts: VerifyOptions{
+				Roots:       []*x509.Certificate{other[2].crt},
+				CurrentTime: now,
+			}},
+			wantErr: ErrUntrustedRoot,
+		},
+		{
+			name: "shouldFailWhenExpired",
+			args: args{chain: certificates(expired), opts: VerifyOptions{
+				Roots:       []*x509.Certificate{expired[2].crt},
+				CurrentTime: now,
+			}},
+			wantErr: ErrCertificateExpired,
+		},
+		{
+			name: "shouldFailWhenIntermediateIsMissing",
+			args: args{chain: []*x509.Certificate{valid[0].crt, valid[2].crt}, opts: VerifyOptions{
+				Roots:       []*x509.Certificate{valid[2].crt},
+				CurrentTime: now,
+			}},
+			wantErr: ErrInvalidChain,
+		},
+		{
+			name: "shouldFailWhenIntermediateIsSwapped",
+			args: args{chain: []*x509.Certificate{valid[0].crt, other[1].crt, valid[2].crt}, opts: VerifyOptions{
+				Roots:       []*x509.Certificate{valid[2].crt},
+				CurrentTime: now,
+			}},
+			wantErr: ErrInvalidChain,
+		},
+		{
+			name: "shouldFailWhenIntermediateIsAttested",
+			args: args{chain: []*x509.Certificate{attestedLeaf.crt, attestedCA.crt, valid[1].crt, valid[2].crt}, opts: VerifyOptions{
+				Roots:       []*x509.Certificate{valid[2].crt},
+				CurrentTime: now,
+			}},
+			wantErr: ErrInvalidChain,
+		},
+		{
+			name: "shouldFailWhenIssuerIsNotCA",
+			args: args{chain: []*x509.Certificate{forged.crt, valid[0].crt, valid[1].crt, valid[2].crt}, opts: VerifyOptions{
+				Roots:       []*x509.Certificate{valid[2].crt},
+				CurrentTime: now,
+			}},
+			wantErr: ErrInvalidChain,
+		},
+		{
+			name: "shouldFailWhenExtensionIsMissing",
+			args: args{chain: certificates(noExtension), opts: VerifyOptions{
+				Roots:       []*x509.Certificate{noExtension[2].crt},
+				CurrentTime: now,
+			}},
+			wantErr: ErrMissingExtension,
+		},
+		{
+			name: "shouldSucceedWithRoot",
+			args: args{chain: certificates(valid), opts: VerifyOptions{
+				Roots:       []*x509.Certificate{valid[2].crt},
+				CurrentTime: now,
+			}},
+			want: keyDesc,
+		},
+		{
+			name: "shouldSucceedWithoutRootInChain",
+			args: args{chain: certificates(valid[:2]), opts: VerifyOptions{
+				Roots:       []*x509.Certificate{valid[2].crt},
+				CurrentTime: now,
+			}},
+			want: keyDesc,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Verify(tt.args.chain, tt.args.opts)
+			if !errors.Is(err, tt.wantErr) {
+				t.Errorf("Verify() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if tt.want == nil {
+				return
+			}
+			got.Raw = nil
+			got.SoftwareEnforced.Raw = nil
+			got.TeeEnforced.Raw = nil
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Verify() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCheckKeyProperties(t *testing.T) {
+	ecKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	edKey, _, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ec, rsaAlgo := AlgoEC, AlgoRSA
+	p256, p384, x25519 := CurveP256, CurveP384, Curve25519
+	size256, size384, size2048 := 256, 384, 2048
+	exponent, otherExponent := int64(65537), int64(3)
+
+	tests := []struct {
+		name      string
+		publicKey crypto.PublicKey
+		keyDesc   *KeyDescription
+		wantErr   error
+	}{
+		{
+			name:      "shouldSucceedWithEC",
+			publicKey: ecKey.Public(),
+			keyDesc: &KeyDescription{TeeEnforced: AuthorizationList{
+				Algorithm: &ec, KeySize: &size256, EcCurve: &p256,
+			}},
+		},
+		{
+			name:      "shouldSucceedWithRSA",
+			publicKey: rsaKey.Public(),
+			keyDesc: &KeyDescription{TeeEnforced: AuthorizationList{
+				Algorithm: &rsaAlgo, KeySize: &size2048, RsaPublicExponent: &exponent,
+			}},
+		},
+		{
+			name:      "shouldSucceedWithCurve25519",
+			publicKey: edKey,
+			keyDesc: &KeyDescription{TeeEnforced: AuthorizationList{
+				Algorithm: &ec, EcCurve: &x25519,
+			}},
+		},
+		{
+			name:      "shouldSucceedWhenSoftwareEnforced",
+			publicKey: ecKey.Public(),
+			keyDesc: &KeyDescription{SoftwareEnforced: AuthorizationList{
+				Algorithm: &ec, KeySize: &size256, EcCurve: &p256,
+			}},
+		},
+		{
+			name:      "shouldFailWhenAlgorithmMismatches",
+			publicKey: ecKey.Public(),
+			keyDesc:   &KeyDescription{TeeEnforced: AuthorizationList{Algorithm: &rsaAlgo}},
+			wantErr:   ErrKeyMismatch,
+		},
+		{
+			name:      "shouldFailWhenKeySizeMismatches",
+			publicKey: ecKey.Public(),
+			keyDesc:   &KeyDescription{TeeEnforced: AuthorizationList{Algorithm: &ec, KeySize: &size384}},
+			wantErr:   ErrKeyMismatch,
+		},
+		{
+			name:      "shouldFailWhenCurveMismatches",
+			publicKey: ecKey.Public(),
+			keyDesc: &KeyDescription{
+				SoftwareEnforced: AuthorizationList{EcCurve: &p256},
+				TeeEnforced:      AuthorizationList{EcCurve: &p384},
+			},
+			wantErr: ErrKeyMismatch,
+		},
+		{
+			name:      "shouldFailWhenExponentMismatches",
+			publicKey: rsaKey.Public(),
+			keyDesc:   &KeyDescription{TeeEnforced: AuthorizationList{RsaPublicExponent: &otherExponent}},
+			wantErr:   ErrKeyMismatch,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := CheckKeyProperties(&x509.Certificate{PublicKey: tt.publicKey}, tt.keyDesc)
+			if !errors.Is(err, tt.wantErr) {
+				t.Errorf("CheckKeyProperties() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+
+	// The test chain leaf key is an ECDSA P-256 key.
+	now := time.Date(2024, time.January, 1, 0, 0, 0, 0, time.UTC)
+	chain := newTestChain(t, &KeyDescription{TeeEnforced: AuthorizationList{KeySize: &size384}}, now.AddDate(1, 0, 0))
+	opts := VerifyOptions{Roots: []*x509.Certificate{chain[2].crt}, CurrentTime: now}
+	if _, err := Verify(certificates(chain), opts); err != nil {
+		t.Errorf("Verify() error = %v, want nil", err)
+	}
+	opts.CheckKeyProperties = true
+	if _, err := Verify(certificates(chain), opts); !errors.Is(err, ErrKeyMismatch) {
+		t.Errorf("Verify() error = %v, want %v", err, ErrKeyMismatch)
+	}
+}
+
+func TestIsGoogleRoot(t *testing.T) {
+	if len(googleRoots()) == 0 {
+		t.Fatal("no Google root public key")
+	}
+
+	chain := newTestChain(t, nil, time.Now().AddDate(1, 0, 0))
+	if IsGoogleRoot(chain[2].crt) {
+		t.Error("IsGoogleRoot() = true, want false")
+	}
+
+	// A certificate carrying one of the root public keys is recognised, whoever signed it.
+	for i, spki := range googleRoots() {
+		pub, err := x509.ParsePKIXPublicKey(spki)
+		if err != nil {
+			t.Fatal(err)
+		}
+		derBytes, err := x509.CreateCertificate(rand.Reader, chain[2].crt, chain[2].crt, pub, chain[2].key)
+		if err != nil {
+			t.Fatal(err)
+		}
+		crt, err := x509.ParseCertificate(derBytes)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !IsGoogleRoot(crt) {
+			t.Errorf("IsGoogleRoot() = false for root public key %d, want true", i)
+		}
+	}
+}